@@ -13,39 +13,39 @@ type AccountInfo struct {
 	Lang                  string                `json:"lang"`
 	TheCode               string                `json:"the_code"`
 	InvitationCode        *string               `json:"invitation_code"`
-	CreditLimit           float64               `json:"credit_limit"`
+	CreditLimit           TRX                   `json:"credit_limit"`
 	LeaderName            *string               `json:"leader_name"`
 	LeaderLevel           int                   `json:"leader_level"`
 	RefEnabled            bool                  `json:"ref_enabled"`
 	IsBanned              bool                  `json:"is_banned"`
 	BalanceRestricted     bool                  `json:"balance_restricted"`
-	Balance               float64               `json:"balance"`
-	EnergyBalance         float64               `json:"energy_balance"`
+	Balance               TRX                   `json:"balance"`
+	EnergyBalance         TRX                   `json:"energy_balance"`
 	Photo                 string                `json:"photo"`
-	StakesSum             float64               `json:"stakes_sum"`
-	StakesProfit          float64               `json:"stakes_profit"`
-	AvailableToUnstakeSum float64               `json:"available_to_unstake_sum"`
+	StakesSum             TRX                   `json:"stakes_sum"`
+	StakesProfit          TRX                   `json:"stakes_profit"`
+	AvailableToUnstakeSum TRX                   `json:"available_to_unstake_sum"`
 	ActiveStakersCount    int                   `json:"active_stakers_count"`
 	Subscription          *Subscription         `json:"subscription"`
 	TwoFA                 bool                  `json:"2fa"`
 	Onboarding            int                   `json:"onboarding"`
-	CreatedAt             string                `json:"created_at"`
-	UpdatedAt             string                `json:"updated_at"`
-	DeletionAt            *string               `json:"deletion_at"`
+	CreatedAt             Timestamp             `json:"created_at"`
+	UpdatedAt             Timestamp             `json:"updated_at"`
+	DeletionAt            *Timestamp            `json:"deletion_at"`
 	Reinvestment          *Reinvestment         `json:"reinvestment"`
 	NotificationSettings  []NotificationSetting `json:"notification_settings"`
 }
 
 type Subscription struct {
-	CreatedAt string `json:"created_at"`
-	ExpiresAt string `json:"expires_at"`
+	CreatedAt Timestamp `json:"created_at"`
+	ExpiresAt Timestamp `json:"expires_at"`
 }
 
 type Reinvestment struct {
-	Wallet    bool   `json:"wallet"`
-	Balance   bool   `json:"balance"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	Wallet    bool      `json:"wallet"`
+	Balance   bool      `json:"balance"`
+	CreatedAt Timestamp `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
 }
 
 type NotificationSetting struct {
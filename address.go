@@ -0,0 +1,179 @@
+package trenergy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ErrInvalidAddress is returned when a TRON address fails validation.
+var ErrInvalidAddress = errors.New("trenergy: invalid TRON address")
+
+// AddressPrefix is the first byte of every TRON address. Mainnet and the
+// current testnets (Nile, Shasta) share it, so an address is valid on both
+// and the network cannot be told from the address itself.
+const AddressPrefix byte = 0x41
+
+// legacyTestnetPrefix was used by the retired TRON testnet ("27..." addresses).
+const legacyTestnetPrefix byte = 0xa0
+
+const addressLen = 21
+
+// Address is a TRON account address: AddressPrefix followed by 20 bytes.
+type Address [addressLen]byte
+
+// ParseAddress parses a base58check address ("T...") or a hex address
+// ("41..." with optional "0x"), verifying the checksum and prefix.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	s = strings.TrimSpace(s)
+
+	var raw []byte
+	switch {
+	case len(s) == 2*addressLen || (len(s) == 2*addressLen+2 && strings.HasPrefix(s, "0x")):
+		b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil {
+			return a, fmt.Errorf("%w: %q: %v", ErrInvalidAddress, s, err)
+		}
+		raw = b
+	default:
+		b, err := base58CheckDecode(s)
+		if err != nil {
+			return a, fmt.Errorf("%w: %q: %v", ErrInvalidAddress, s, err)
+		}
+		raw = b
+	}
+
+	if len(raw) != addressLen {
+		return a, fmt.Errorf("%w: %q: wrong length", ErrInvalidAddress, s)
+	}
+	switch raw[0] {
+	case AddressPrefix:
+	case legacyTestnetPrefix:
+		return a, fmt.Errorf("%w: %q: legacy testnet address", ErrInvalidAddress, s)
+	default:
+		return a, fmt.Errorf("%w: %q: unknown prefix 0x%02x", ErrInvalidAddress, s, raw[0])
+	}
+	copy(a[:], raw)
+	return a, nil
+}
+
+// MustParseAddress is like ParseAddress but panics on error.
+func MustParseAddress(s string) Address {
+	a, err := ParseAddress(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// ValidateAddress reports whether s is a valid TRON address.
+// The returned error wraps ErrInvalidAddress.
+func ValidateAddress(s string) error {
+	_, err := ParseAddress(s)
+	return err
+}
+
+// IsZero reports whether a is the zero value.
+func (a Address) IsZero() bool {
+	return a == Address{}
+}
+
+// String returns the base58check form, e.g. "TG5F5NDG...".
+func (a Address) String() string {
+	if a.IsZero() {
+		return ""
+	}
+	return base58CheckEncode(a[:])
+}
+
+// Hex returns the hex form including the 41 prefix.
+func (a Address) Hex() string {
+	return hex.EncodeToString(a[:])
+}
+
+// MarshalText encodes the address in base58check form.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText accepts the base58check or hex form. Empty text leaves the
+// zero address.
+func (a *Address) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*a = Address{}
+		return nil
+	}
+	parsed, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var bigRadix = big.NewInt(58)
+
+func checksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
+
+func base58CheckEncode(payload []byte) string {
+	data := append(bytes.Clone(payload), checksum(payload)...)
+
+	n := new(big.Int).SetBytes(data)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, bigRadix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func base58CheckDecode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty")
+	}
+	n := new(big.Int)
+	for _, r := range s {
+		idx := strings.IndexRune(base58Alphabet, r)
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", r)
+		}
+		n.Mul(n, bigRadix)
+		n.Add(n, big.NewInt(int64(idx)))
+	}
+	decoded := n.Bytes()
+	for _, r := range s {
+		if r != rune(base58Alphabet[0]) {
+			break
+		}
+		decoded = append([]byte{0}, decoded...)
+	}
+	if len(decoded) < 5 {
+		return nil, errors.New("too short")
+	}
+	payload, sum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	if !bytes.Equal(checksum(payload), sum) {
+		return nil, errors.New("checksum mismatch")
+	}
+	return payload, nil
+}
@@ -0,0 +1,64 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+)
+
+func TestParseAddress(t *testing.T) {
+	const b58 = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	const hexForm = "41a614f803b6fd780986a42c78ec9c7f77e6ded13c"
+
+	a, err := trenergy.ParseAddress(b58)
+	if err != nil {
+		t.Fatalf("ParseAddress failed: %v", err)
+	}
+	if a.Hex() != hexForm {
+		t.Errorf("unexpected hex %s", a.Hex())
+	}
+	for _, in := range []string{hexForm, "0x" + hexForm} {
+		h, err := trenergy.ParseAddress(in)
+		if err != nil || h != a || h.String() != b58 {
+			t.Errorf("%s: got %s (%v)", in, h, err)
+		}
+	}
+
+	for _, bad := range []string{
+		"",
+		"TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6u", // checksum
+		"TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6",  // length
+		"TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj0t", // alphabet
+		"42a614f803b6fd780986a42c78ec9c7f77e6ded13c",
+	} {
+		if err := trenergy.ValidateAddress(bad); !errors.Is(err, trenergy.ErrInvalidAddress) {
+			t.Errorf("%q: expected ErrInvalidAddress, got %v", bad, err)
+		}
+	}
+}
+
+func TestAddressValidatedBeforeSending(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	}))
+	defer srv.Close()
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+	ctx := context.Background()
+	typo := "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6T"
+
+	if _, err := client.CreateWithdrawal(ctx, trenergy.OneTRX, typo, ""); !errors.Is(err, trenergy.ErrInvalidAddress) {
+		t.Errorf("CreateWithdrawal: expected ErrInvalidAddress, got %v", err)
+	}
+	if _, err := client.ActivateAddress(ctx, typo); !errors.Is(err, trenergy.ErrInvalidAddress) {
+		t.Errorf("ActivateAddress: expected ErrInvalidAddress, got %v", err)
+	}
+	if _, err := client.CreateBootstrapOrder(ctx, trenergy.ConsumerParams{
+		Address: typo, PaymentPeriod: trenergy.PaymentPeriod1H, ResourceAmount: 65000, Resource: trenergy.ResourceEnergy,
+	}); !errors.Is(err, trenergy.ErrInvalidAddress) {
+		t.Errorf("CreateBootstrapOrder: expected ErrInvalidAddress, got %v", err)
+	}
+}
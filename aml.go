@@ -12,7 +12,7 @@ type AMLCheck struct {
 	TxID       *string          `json:"txid"`
 	Status     string           `json:"status"` // e.g. "completed"
 	Context    *AMLCheckContext `json:"context"`
-	CreatedAt  string           `json:"created_at"`
+	CreatedAt  Timestamp        `json:"created_at"`
 }
 
 type AMLCheckContext struct {
@@ -40,14 +40,16 @@ func (c *Client) ListAMLChecks(ctx context.Context, page int) (*APIResponse[[]AM
 
 // CheckAML performs a new AML check.
 func (c *Client) CheckAML(ctx context.Context, address string, txid string) (*APIResponse[*AMLCheck], error) {
-	data := make(map[string]string)
-	data["address"] = address
-	if txid != "" {
-		data["txid"] = txid
+	if err := ValidateAddress(address); err != nil {
+		return nil, err
 	}
+	form := struct {
+		Address string `form:"address"`
+		TxID    string `form:"txid,omitempty"`
+	}{Address: address, TxID: txid}
 
 	var resp APIResponse[*AMLCheck]
-	err := c.postMultipart(ctx, "/api/aml/check", data, &resp)
+	err := c.sendForm(ctx, "POST", "/api/aml/check", bodyMultipart, form, &resp)
 	if err != nil {
 		return nil, err
 	}
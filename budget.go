@@ -0,0 +1,264 @@
+package trenergy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is matched by a *BudgetError.
+var ErrBudgetExceeded = errors.New("trenergy: budget exceeded")
+
+// Budget limits how much TRX a Client may spend. Zero limits are not
+// enforced. The hour and day limits are rolling windows.
+//
+// The budget is checked before CreateBootstrapOrder, MassTrx,
+// ActivateAddress, CreateStake and CreateWithdrawal send anything. Only calls
+// made through the Client are counted. The limits are enforced exactly among
+// the calls of one Client: calls in flight are held in memory, so Clients that
+// share a Store see each other's spending only once it is recorded and may
+// together exceed a limit by the calls they have in flight.
+//
+// A call that the server rejects, or that fails validation, does not count.
+// A call that fails in a way that may still have been applied, such as a
+// timeout, a connection reset, a 408 or 409, or a 5xx response, counts at its
+// estimated cost.
+type Budget struct {
+	// MaxPerOperation limits the cost of a single call, e.g. one order or
+	// the total of one MassTrx.
+	MaxPerOperation TRX
+	MaxPerHour      TRX
+	MaxPerDay       TRX
+	// MaxOutstanding limits the total cost of orders whose payment period
+	// has not ended yet.
+	MaxOutstanding TRX
+	// Store persists the spending. The default is a MemoryBudgetStore.
+	Store BudgetStore
+	// Now overrides the clock, for tests.
+	Now func() time.Time
+}
+
+// WithBudget enforces b on the paid operations of the Client.
+func WithBudget(b Budget) Option {
+	return func(c *Client) {
+		if b.Store == nil {
+			b.Store = NewMemoryBudgetStore()
+		}
+		if b.Now == nil {
+			b.Now = time.Now
+		}
+		c.budget = &budgetGuard{budget: b}
+	}
+}
+
+// BudgetUsage is the spending counted against a Budget.
+type BudgetUsage struct {
+	LastHour    TRX
+	LastDay     TRX
+	Outstanding TRX
+}
+
+// BudgetError is returned when a call would exceed a Budget limit.
+type BudgetError struct {
+	Operation string
+	// Limit names the limit that would be exceeded: "operation", "hour",
+	// "day" or "outstanding".
+	Limit     string
+	Max       TRX
+	Requested TRX
+	Usage     BudgetUsage
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("trenergy: budget exceeded: %s of %s TRX would exceed the %s limit of %s TRX (last hour %s, last day %s, outstanding %s)",
+		e.Operation, e.Requested, e.Limit, e.Max, e.Usage.LastHour, e.Usage.LastDay, e.Usage.Outstanding)
+}
+
+// Is reports whether target is ErrBudgetExceeded.
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// BudgetUsage returns the current spending, or zero usage if the Client has
+// no budget.
+func (c *Client) BudgetUsage(ctx context.Context) (BudgetUsage, error) {
+	if c.budget == nil {
+		return BudgetUsage{}, nil
+	}
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+	return c.budget.usage(ctx, c.budget.budget.Now())
+}
+
+// budgetGuard enforces a Budget. Spending is reserved while a call is in
+// flight so that concurrent calls cannot overshoot together, and recorded in
+// the store once the call succeeds.
+type budgetGuard struct {
+	budget Budget
+
+	mu      sync.Mutex
+	pending map[*reservation]SpendRecord
+	// unsaved holds records the store failed to save; they still count and
+	// are saved again with the next record.
+	unsaved []SpendRecord
+}
+
+type budgetLimit struct {
+	name      string
+	max, used TRX
+}
+
+// reservation is spending held for a call in flight. A nil reservation, from
+// a Client without a budget, does nothing.
+type reservation struct {
+	guard *budgetGuard
+}
+
+// reserve checks that amount fits the budget and holds it. period is the
+// payment period of an order, counted towards MaxOutstanding, or zero.
+func (c *Client) reserve(ctx context.Context, op string, amount TRX, period time.Duration) (*reservation, error) {
+	g := c.budget
+	if g == nil {
+		return nil, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.budget.Now()
+	usage, err := g.usage(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	limits := []budgetLimit{
+		{"operation", g.budget.MaxPerOperation, 0},
+		{"hour", g.budget.MaxPerHour, usage.LastHour},
+		{"day", g.budget.MaxPerDay, usage.LastDay},
+	}
+	if period > 0 {
+		limits = append(limits, budgetLimit{"outstanding", g.budget.MaxOutstanding, usage.Outstanding})
+	}
+	for _, l := range limits {
+		if l.max > 0 && l.used+amount > l.max {
+			return nil, &BudgetError{Operation: op, Limit: l.name, Max: l.max, Requested: amount, Usage: usage}
+		}
+	}
+
+	r := &reservation{guard: g}
+	rec := SpendRecord{Operation: op, Amount: amount, At: now}
+	if period > 0 {
+		rec.Until = now.Add(period)
+	}
+	if g.pending == nil {
+		g.pending = make(map[*reservation]SpendRecord)
+	}
+	g.pending[r] = rec
+	return r, nil
+}
+
+// rejected reports whether err means a paid call was refused without taking
+// effect: client-side validation, the budget itself, a 4xx or business error
+// response, or a mass change the server reported as not applied. Other
+// failures, such as timeouts, connection resets, 408 and 409 responses and
+// 5xx responses, may have been applied.
+func rejected(err error) bool {
+	var apiErr *APIError
+	var budgetErr *BudgetError
+	switch {
+	case errors.Is(err, ErrInvalidParams), errors.Is(err, ErrInvalidAddress),
+		errors.Is(err, ErrNotApplied), errors.As(err, &budgetErr):
+		return true
+	case errors.As(err, &apiErr):
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusConflict:
+			return false
+		}
+		return apiErr.StatusCode < 500
+	}
+	return false
+}
+
+// commit records amount, which may differ from the reserved amount, as spent.
+func (r *reservation) commit(ctx context.Context, amount TRX) {
+	if r == nil {
+		return
+	}
+	g := r.guard
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	rec, ok := g.pending[r]
+	if !ok {
+		return
+	}
+	delete(g.pending, r)
+	if amount <= 0 {
+		return
+	}
+	rec.Amount = amount
+	g.unsaved = append(g.unsaved, rec)
+	for len(g.unsaved) > 0 {
+		if g.budget.Store.Add(ctx, g.unsaved[0]) != nil {
+			return
+		}
+		g.unsaved = g.unsaved[1:]
+	}
+}
+
+// fail settles the reservation of a call that returned err. A rejected call
+// is released; any other failure may have been applied, so the reserved
+// amount is committed.
+func (r *reservation) fail(ctx context.Context, err error) {
+	if r == nil {
+		return
+	}
+	if rejected(err) {
+		r.release()
+		return
+	}
+	r.guard.mu.Lock()
+	amount := r.guard.pending[r].Amount
+	r.guard.mu.Unlock()
+	r.commit(ctx, amount)
+}
+
+// release drops the reservation of a call that was not made.
+func (r *reservation) release() {
+	if r == nil {
+		return
+	}
+	r.guard.mu.Lock()
+	defer r.guard.mu.Unlock()
+	delete(r.guard.pending, r)
+}
+
+// usage sums the stored, unsaved and pending spending. g.mu must be held.
+func (g *budgetGuard) usage(ctx context.Context, now time.Time) (BudgetUsage, error) {
+	stored, err := g.budget.Store.Load(ctx)
+	if err != nil {
+		return BudgetUsage{}, fmt.Errorf("trenergy: loading budget: %w", err)
+	}
+	var u BudgetUsage
+	add := func(r SpendRecord) {
+		if now.Sub(r.At) < time.Hour {
+			u.LastHour += r.Amount
+		}
+		if now.Sub(r.At) < 24*time.Hour {
+			u.LastDay += r.Amount
+		}
+		if r.Until.After(now) {
+			u.Outstanding += r.Amount
+		}
+	}
+	for _, r := range stored {
+		add(r)
+	}
+	for _, r := range g.unsaved {
+		add(r)
+	}
+	for _, r := range g.pending {
+		add(r)
+	}
+	return u, nil
+}
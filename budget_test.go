@@ -0,0 +1,171 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/trenergytest"
+)
+
+// manualClock is a clock advanced by the test.
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// An energy order of 1000 units for 15 minutes costs 0.065 TRX on the fake.
+const orderCost trenergy.TRX = 65_000
+
+func TestBudgetLimits(t *testing.T) {
+	clk := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100*trenergy.OneTRX), trenergytest.WithClock(clk.Now))
+	defer fake.Close()
+	ctx := context.Background()
+	params := bulkParams()[0]
+
+	client := fake.NewClient(trenergy.WithBudget(trenergy.Budget{MaxPerOperation: orderCost - 1, Now: clk.Now}))
+	_, err := client.CreateBootstrapOrder(ctx, params)
+	var budgetErr *trenergy.BudgetError
+	if !errors.Is(err, trenergy.ErrBudgetExceeded) || !errors.As(err, &budgetErr) || budgetErr.Limit != "operation" {
+		t.Fatalf("expected the operation limit, got %v", err)
+	}
+	if budgetErr.Requested != orderCost || len(fake.Consumers()) != 0 {
+		t.Errorf("unexpected error %+v", budgetErr)
+	}
+
+	client = fake.NewClient(trenergy.WithBudget(trenergy.Budget{MaxPerHour: 3 * orderCost / 2, Now: clk.Now}))
+	if _, err := client.CreateBootstrapOrder(ctx, params); err != nil {
+		t.Fatalf("CreateBootstrapOrder failed: %v", err)
+	}
+	_, err = client.CreateBootstrapOrder(ctx, params)
+	if !errors.As(err, &budgetErr) || budgetErr.Limit != "hour" || budgetErr.Usage.LastHour != orderCost {
+		t.Fatalf("expected the hourly limit, got %v", err)
+	}
+	clk.Advance(time.Hour)
+	if _, err := client.CreateBootstrapOrder(ctx, params); err != nil {
+		t.Fatalf("CreateBootstrapOrder failed after an hour: %v", err)
+	}
+	usage, err := client.BudgetUsage(ctx)
+	if err != nil || usage.LastHour != orderCost || usage.LastDay != 2*orderCost || usage.Outstanding != orderCost {
+		t.Errorf("unexpected usage %+v: %v", usage, err)
+	}
+
+	client = fake.NewClient(trenergy.WithBudget(trenergy.Budget{MaxOutstanding: orderCost, Now: clk.Now}))
+	if _, err := client.CreateBootstrapOrder(ctx, params); err != nil {
+		t.Fatalf("CreateBootstrapOrder failed: %v", err)
+	}
+	if _, err := client.CreateBootstrapOrder(ctx, params); !errors.As(err, &budgetErr) || budgetErr.Limit != "outstanding" {
+		t.Fatalf("expected the outstanding limit, got %v", err)
+	}
+	clk.Advance(15 * time.Minute)
+	if _, err := client.CreateBootstrapOrder(ctx, params); err != nil {
+		t.Errorf("CreateBootstrapOrder failed after the order ended: %v", err)
+	}
+}
+
+func TestBudgetOperations(t *testing.T) {
+	fake, _, ids := massSetup(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "budget.json")
+	budget := trenergy.Budget{MaxPerDay: 5 * trenergy.OneTRX, Store: trenergy.NewFileBudgetStore(path)}
+	client := fake.NewClient(trenergy.WithBudget(budget))
+
+	if _, err := client.MassTrx(ctx, trenergy.MassTrxParams{Consumers: ids, Amount: trenergy.OneTRX},
+		trenergy.WithBatchSize(2)); err != nil {
+		t.Fatalf("MassTrx failed: %v", err)
+	}
+	if _, err := client.ActivateAddress(ctx, bulkAddresses[0]); !errors.Is(err, trenergy.ErrBudgetExceeded) {
+		t.Errorf("expected ActivateAddress to exceed the budget, got %v", err)
+	}
+
+	// A restarted client reads the spending from the store.
+	budget.Store = trenergy.NewFileBudgetStore(path)
+	client = fake.NewClient(trenergy.WithBudget(budget))
+	usage, err := client.BudgetUsage(ctx)
+	if err != nil || usage.LastDay != 4*trenergy.OneTRX {
+		t.Fatalf("unexpected usage %+v: %v", usage, err)
+	}
+
+	// A failed call does not count.
+	fake.SetBalance(0)
+	if _, err := client.CreateWithdrawal(ctx, trenergy.OneTRX/2, "", ""); !errors.Is(err, trenergy.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	fake.SetBalance(100 * trenergy.OneTRX)
+	if _, err := client.CreateStake(ctx, trenergy.CreateStakeParams{TrxAmount: trenergy.OneTRX}); err != nil {
+		t.Fatalf("CreateStake failed: %v", err)
+	}
+	if _, err := client.CreateWithdrawal(ctx, trenergy.OneTRX/2, "", ""); !errors.Is(err, trenergy.ErrBudgetExceeded) {
+		t.Errorf("expected the daily limit, got %v", err)
+	}
+}
+
+func TestBudgetCountsAmbiguousFailures(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+	defer fake.Close()
+	ctx := context.Background()
+	client := fake.NewClient(trenergy.WithBudget(trenergy.Budget{MaxPerDay: 10 * trenergy.OneTRX}))
+
+	// A 422 is a rejection; a 502 or a 408 may have come after the order was
+	// placed.
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/bootstrap-order", Status: http.StatusUnprocessableEntity})
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/bootstrap-order", Status: http.StatusBadGateway})
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/bootstrap-order", Status: http.StatusRequestTimeout})
+	for range 3 {
+		if _, err := client.CreateBootstrapOrder(ctx, bulkParams()[0]); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	usage, err := client.BudgetUsage(ctx)
+	if err != nil || usage.LastDay != 2*orderCost || usage.Outstanding != 2*orderCost {
+		t.Errorf("expected only the ambiguous failures to count, got %+v (%v)", usage, err)
+	}
+}
+
+func TestFileBudgetStoreShared(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "budget.jsonl")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Two stores on one file stand in for two processes.
+	a, b := trenergy.NewFileBudgetStore(path), trenergy.NewFileBudgetStore(path)
+	if _, err := a.Load(ctx); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := a.Add(ctx, trenergy.SpendRecord{Operation: "stake", Amount: trenergy.OneTRX, At: now.Add(-48 * time.Hour)}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := b.Add(ctx, trenergy.SpendRecord{Operation: "withdrawal", Amount: 2 * trenergy.OneTRX, At: now}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	for _, s := range []*trenergy.FileBudgetStore{a, b} {
+		if records, err := s.Load(ctx); err != nil || len(records) != 2 {
+			t.Errorf("expected both records, got %+v (%v)", records, err)
+		}
+	}
+
+	if err := a.Compact(ctx, now); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if records, err := b.Load(ctx); err != nil || len(records) != 1 || records[0].Operation != "withdrawal" {
+		t.Errorf("expected the expired record to be dropped, got %+v (%v)", records, err)
+	}
+}
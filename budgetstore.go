@@ -0,0 +1,163 @@
+package trenergy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SpendRecord is a paid call counted against a Budget.
+type SpendRecord struct {
+	Operation string    `json:"operation"`
+	Amount    TRX       `json:"amount"`
+	At        time.Time `json:"at"`
+	// Until is when the payment period of an order ends.
+	Until time.Time `json:"until,omitzero"`
+}
+
+// expired reports whether r no longer counts against any limit at now.
+func (r SpendRecord) expired(now time.Time) bool {
+	return now.Sub(r.At) >= 24*time.Hour && !r.Until.After(now)
+}
+
+// BudgetStore persists the spending of a Budget. Stores may drop records that
+// no longer count, i.e. older than a day with their payment period over.
+type BudgetStore interface {
+	Load(ctx context.Context) ([]SpendRecord, error)
+	Add(ctx context.Context, r SpendRecord) error
+}
+
+// MemoryBudgetStore is a BudgetStore that keeps records in memory.
+type MemoryBudgetStore struct {
+	mu      sync.Mutex
+	records []SpendRecord
+}
+
+// NewMemoryBudgetStore returns an empty MemoryBudgetStore.
+func NewMemoryBudgetStore() *MemoryBudgetStore {
+	return &MemoryBudgetStore{}
+}
+
+func (m *MemoryBudgetStore) Load(context.Context) ([]SpendRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.records), nil
+}
+
+func (m *MemoryBudgetStore) Add(_ context.Context, r SpendRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = slices.DeleteFunc(m.records, func(old SpendRecord) bool { return old.expired(r.At) })
+	m.records = append(m.records, r)
+	return nil
+}
+
+// FileBudgetStore is a BudgetStore backed by a file of JSON records, one per
+// line, so that the budget survives restarts. Each record is appended with a
+// single write and the file is read again on every Load, so Clients in other
+// processes using the same file see the recorded spending, but not their
+// calls in flight; see Budget.
+//
+// Every paid call reads the whole file, which grows by one line per record.
+// Call Compact from time to time, e.g. at startup, to keep it small.
+type FileBudgetStore struct {
+	path string
+}
+
+// NewFileBudgetStore returns a FileBudgetStore for path. The file is created
+// on the first record.
+func NewFileBudgetStore(path string) *FileBudgetStore {
+	return &FileBudgetStore{path: path}
+}
+
+func (f *FileBudgetStore) Load(context.Context) ([]SpendRecord, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []SpendRecord
+	for len(data) > 0 {
+		line, rest, complete := bytes.Cut(data, []byte("\n"))
+		data = rest
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var r SpendRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			if !complete {
+				// A write cut short by a crash.
+				break
+			}
+			return nil, fmt.Errorf("trenergy: reading budget %s: %w", f.path, err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func (f *FileBudgetStore) Add(_ context.Context, r SpendRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Compact rewrites the file without the records that no longer count at now.
+// Records that another process appends while it runs may be lost, so call it
+// only when no other process uses the file, e.g. at startup.
+func (f *FileBudgetStore) Compact(ctx context.Context, now time.Time) error {
+	records, err := f.Load(ctx)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if r.expired(now) {
+			continue
+		}
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(f.path, buf.Bytes())
+}
+
+// writeFileAtomic replaces the file at path with data, so that readers never
+// see a partial write.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,325 @@
+package trenergy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrBulkStopped is the error of items that were not attempted because an
+// earlier item failed and StopOnFirstError was set.
+var ErrBulkStopped = errors.New("trenergy: stopped after an earlier failure")
+
+// BootstrapResult is the outcome of one item of CreateBootstrapOrders.
+type BootstrapResult struct {
+	// Index is the position of the item in the input.
+	Index    int
+	Params   ConsumerParams
+	Consumer *Consumer
+	Err      error
+	// Attempts is the number of times the item was tried. It is zero for
+	// items that were skipped or restored from the checkpoint.
+	Attempts int
+	// Resumed reports whether the consumer was restored from the checkpoint.
+	Resumed bool
+}
+
+// BulkOption configures CreateBootstrapOrders.
+type BulkOption func(*bulkConfig)
+
+type bulkConfig struct {
+	workers     int
+	maxAttempts int
+	stop        bool
+	checkpoint  string
+	progress    func(BootstrapResult)
+}
+
+// WithWorkers sets how many orders are created concurrently. The default is 4.
+func WithWorkers(n int) BulkOption {
+	return func(c *bulkConfig) {
+		c.workers = n
+	}
+}
+
+// WithItemAttempts sets how many times an item is tried. Only failures that
+// mean the order was not placed are retried: a 429 with a Retry-After header
+// and a connection that could not be established. The default is 3.
+func WithItemAttempts(n int) BulkOption {
+	return func(c *bulkConfig) {
+		c.maxAttempts = n
+	}
+}
+
+// StopOnFirstError stops starting new items once one fails. Items that were
+// not started fail with ErrBulkStopped. By default every item is attempted.
+func StopOnFirstError() BulkOption {
+	return func(c *bulkConfig) {
+		c.stop = true
+	}
+}
+
+// WithCheckpoint records created consumers in the JSON file at path. Items
+// found in the file are not created again, so a failed run can be resumed by
+// calling CreateBootstrapOrders with the same input and path.
+//
+// Items whose order may have been placed despite the error, e.g. after a 503
+// or a timeout, are recorded as unknown. On resume they are looked up with
+// FindConsumerByAddress first and only created again if no consumer exists
+// for the address. A consumer that existed before the first run is taken
+// for the created one.
+func WithCheckpoint(path string) BulkOption {
+	return func(c *bulkConfig) {
+		c.checkpoint = path
+	}
+}
+
+// WithBulkProgress calls fn as each item finishes. It may be called
+// concurrently.
+func WithBulkProgress(fn func(BootstrapResult)) BulkOption {
+	return func(c *bulkConfig) {
+		c.progress = fn
+	}
+}
+
+// CreateBootstrapOrders creates a bootstrap order for each item of params
+// using a bounded number of workers. It returns one result per item, in
+// input order, and an error joining the failures.
+func (c *Client) CreateBootstrapOrders(ctx context.Context, params []ConsumerParams, opts ...BulkOption) ([]BootstrapResult, error) {
+	cfg := bulkConfig{workers: 4, maxAttempts: 3, progress: func(BootstrapResult) {}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.workers = max(cfg.workers, 1)
+	cfg.maxAttempts = max(cfg.maxAttempts, 1)
+
+	var cp *checkpoint
+	if cfg.checkpoint != "" {
+		var err error
+		if cp, err = loadCheckpoint(cfg.checkpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]BootstrapResult, len(params))
+	for i, p := range params {
+		results[i] = BootstrapResult{Index: i, Params: p}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var cpErr error
+	var cpMu sync.Mutex
+	for range min(cfg.workers, len(params)) {
+		wg.Go(func() {
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				r := &results[i]
+				if cp.isUnknown(r.Index, r.Params.Address) {
+					c.resolveUnknown(ctx, r)
+				}
+				if r.Consumer == nil && r.Err == nil {
+					c.createWithRetry(ctx, r, cfg.maxAttempts)
+				}
+				if err := cp.record(r); err != nil {
+					cpMu.Lock()
+					cpErr = errors.Join(cpErr, err)
+					cpMu.Unlock()
+				}
+				cfg.progress(*r)
+				if r.Err != nil && cfg.stop {
+					cancel(ErrBulkStopped)
+				}
+			}
+		})
+	}
+
+feed:
+	for i := range results {
+		r := &results[i]
+		if consumer := cp.get(i, r.Params.Address); consumer != nil {
+			r.Consumer = consumer
+			r.Resumed = true
+			cfg.progress(*r)
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var errs []error
+	for i := range results {
+		r := &results[i]
+		if r.Consumer == nil && r.Err == nil {
+			r.Err = context.Cause(ctx)
+			continue
+		}
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("item %d (%s): %w", r.Index, r.Params.Address, r.Err))
+		}
+	}
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, ErrBulkStopped) {
+		errs = append(errs, err)
+	}
+	errs = append(errs, cpErr)
+	return results, errors.Join(errs...)
+}
+
+// createWithRetry creates the order of r, retrying failures after which the
+// order is known not to have been placed.
+func (c *Client) createWithRetry(ctx context.Context, r *BootstrapResult, maxAttempts int) {
+	for attempt := 1; ; attempt++ {
+		r.Attempts = attempt
+		resp, err := c.CreateBootstrapOrder(ctx, r.Params)
+		if err == nil && resp.Data == nil {
+			// The order may have been placed, so this is not retried.
+			err = fmt.Errorf("trenergy: bootstrap order for %s returned no consumer", r.Params.Address)
+		}
+		if err == nil {
+			r.Consumer, r.Err = resp.Data, nil
+			return
+		}
+		r.Err = err
+		wait, ok := c.notPlaced(err, attempt)
+		if attempt >= maxAttempts || !ok {
+			return
+		}
+		if sleepContext(ctx, wait) != nil {
+			return
+		}
+	}
+}
+
+// resolveUnknown looks up the consumer of an item whose earlier attempt
+// failed ambiguously. If one exists, it is taken as the created consumer.
+func (c *Client) resolveUnknown(ctx context.Context, r *BootstrapResult) {
+	found, err := c.FindConsumerByAddress(ctx, r.Params.Address)
+	switch {
+	case err == nil:
+		r.Consumer = found
+		r.Resumed = true
+	case !errors.Is(err, ErrNotFound):
+		r.Err = fmt.Errorf("trenergy: resolving earlier attempt: %w", err)
+	}
+}
+
+// notPlaced reports whether a failed create certainly did not reach the
+// server, and how long to wait before trying again. Other failures, such
+// as a 503 or a timeout, may have placed the order and are not retried.
+func (c *Client) notPlaced(err error, attempt int) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode != http.StatusTooManyRequests {
+			return 0, false
+		}
+		return c.retry.serverDelay(apiErr.Header)
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return c.retry.backoff(attempt), true
+	}
+	return 0, false
+}
+
+// checkpoint is the file of consumers created by CreateBootstrapOrders,
+// keyed by input index and address so that a changed input is not matched.
+type checkpoint struct {
+	path string
+	mu   sync.Mutex
+	// Completed and Unknown are the encoded form. Unknown holds the error of
+	// items whose order may have been placed.
+	Completed map[string]*Consumer `json:"completed"`
+	Unknown   map[string]string    `json:"unknown,omitempty"`
+}
+
+func checkpointKey(index int, address string) string {
+	return fmt.Sprintf("%d:%s", index, address)
+}
+
+func loadCheckpoint(path string) (*checkpoint, error) {
+	cp := &checkpoint{path: path, Completed: make(map[string]*Consumer), Unknown: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("trenergy: reading checkpoint %s: %w", path, err)
+	}
+	if cp.Completed == nil {
+		cp.Completed = make(map[string]*Consumer)
+	}
+	if cp.Unknown == nil {
+		cp.Unknown = make(map[string]string)
+	}
+	return cp, nil
+}
+
+// get returns the consumer recorded for the item, or nil. It is safe to call
+// on a nil checkpoint.
+func (cp *checkpoint) get(index int, address string) *Consumer {
+	if cp == nil {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.Completed[checkpointKey(index, address)]
+}
+
+// isUnknown reports whether an earlier attempt of the item failed
+// ambiguously. It is safe to call on a nil checkpoint.
+func (cp *checkpoint) isUnknown(index int, address string) bool {
+	if cp == nil {
+		return false
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	_, ok := cp.Unknown[checkpointKey(index, address)]
+	return ok
+}
+
+// record saves the outcome of r and rewrites the file: a created consumer as
+// completed and an ambiguous failure as unknown. Rejected items are not
+// recorded. It is safe to call on a nil checkpoint.
+func (cp *checkpoint) record(r *BootstrapResult) error {
+	if cp == nil {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	key := checkpointKey(r.Index, r.Params.Address)
+	switch {
+	case r.Err == nil && r.Consumer != nil:
+		cp.Completed[key] = r.Consumer
+		delete(cp.Unknown, key)
+	case r.Err != nil && !rejected(r.Err):
+		cp.Unknown[key] = r.Err.Error()
+	default:
+		return nil
+	}
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(cp.path, data)
+}
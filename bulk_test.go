@@ -0,0 +1,175 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/trenergytest"
+)
+
+var bulkAddresses = []string{
+	"TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF",
+	"TCsKjXeT652tbDhjVzVdFEtacXNwhBCcDQ",
+	"TKzxdSv2FZKQrEqkKVgp5DcwEXBEKMg2Ax",
+	"TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t",
+}
+
+func bulkParams() []trenergy.ConsumerParams {
+	params := make([]trenergy.ConsumerParams, len(bulkAddresses))
+	for i, addr := range bulkAddresses {
+		params[i] = trenergy.ConsumerParams{
+			Address:        addr,
+			PaymentPeriod:  trenergy.PaymentPeriod15M,
+			ResourceAmount: 1000,
+			Resource:       trenergy.ResourceEnergy,
+		}
+	}
+	return params
+}
+
+func TestCreateBootstrapOrders(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+	defer fake.Close()
+	client := fake.NewClient()
+
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/bootstrap-order", Status: 429,
+		Header: http.Header{"Retry-After": {"0"}}})
+	results, err := client.CreateBootstrapOrders(context.Background(), bulkParams(),
+		trenergy.WithWorkers(2), trenergy.WithItemAttempts(2))
+	if err != nil {
+		t.Fatalf("CreateBootstrapOrders failed: %v", err)
+	}
+	attempts := 0
+	for i, r := range results {
+		if r.Index != i || r.Consumer == nil || r.Consumer.Address != bulkAddresses[i] {
+			t.Errorf("unexpected result %d: %+v", i, r)
+		}
+		attempts += r.Attempts
+	}
+	if attempts != len(bulkAddresses)+1 {
+		t.Errorf("expected one retry, got %d attempts", attempts)
+	}
+	if n := len(fake.Consumers()); n != len(bulkAddresses) {
+		t.Errorf("expected %d consumers, got %d", len(bulkAddresses), n)
+	}
+}
+
+func TestCreateBootstrapOrdersAmbiguousFailure(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+	defer fake.Close()
+	client := fake.NewClient()
+
+	// A 503 may come after the order was placed, and so does a 429 without
+	// Retry-After from a proxy; neither is retried.
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/bootstrap-order", Status: 503})
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/bootstrap-order", Status: 429})
+	results, err := client.CreateBootstrapOrders(context.Background(), bulkParams()[:2],
+		trenergy.WithWorkers(1), trenergy.WithItemAttempts(3))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, r := range results {
+		if r.Err == nil || r.Attempts != 1 {
+			t.Errorf("expected item %d to fail without a retry, got %+v", r.Index, r)
+		}
+	}
+}
+
+func TestCreateBootstrapOrdersStop(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+	defer fake.Close()
+	client := fake.NewClient()
+
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/bootstrap-order", Status: 422})
+	results, err := client.CreateBootstrapOrders(context.Background(), bulkParams(),
+		trenergy.WithWorkers(1), trenergy.StopOnFirstError())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if results[0].Err == nil || results[0].Attempts != 1 {
+		t.Errorf("expected the first item to fail once, got %+v", results[0])
+	}
+	for _, r := range results[1:] {
+		if !errors.Is(r.Err, trenergy.ErrBulkStopped) || r.Attempts != 0 {
+			t.Errorf("expected item %d to be skipped, got %+v", r.Index, r)
+		}
+	}
+	if n := len(fake.Consumers()); n != 0 {
+		t.Errorf("expected no consumers, got %d", n)
+	}
+}
+
+func TestCreateBootstrapOrdersResume(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+	defer fake.Close()
+	client := fake.NewClient()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	params := bulkParams()
+	params[2].ResourceAmount = 0 // rejected by validation
+	results, err := client.CreateBootstrapOrders(ctx, params, trenergy.WithCheckpoint(path))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed != 1 || len(fake.Consumers()) != len(params)-1 {
+		t.Fatalf("expected one failure, got %d", failed)
+	}
+
+	params[2].ResourceAmount = 1000
+	results, err = client.CreateBootstrapOrders(ctx, params, trenergy.WithCheckpoint(path))
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	for _, r := range results {
+		if resumed := r.Index != 2; r.Resumed != resumed || r.Consumer == nil {
+			t.Errorf("unexpected result %d: %+v", r.Index, r)
+		}
+	}
+	if n := len(fake.Consumers()); n != len(params) {
+		t.Errorf("expected %d consumers, got %d", len(params), n)
+	}
+}
+
+func TestCreateBootstrapOrdersResumeAfterUnknown(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+	defer fake.Close()
+	client := fake.NewClient()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	params := bulkParams()[:2]
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/bootstrap-order", Status: 503})
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/bootstrap-order", Status: 503})
+	if _, err := client.CreateBootstrapOrders(ctx, params, trenergy.WithWorkers(1), trenergy.WithCheckpoint(path)); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// The first order went through despite the 503, the second did not.
+	if _, err := client.CreateBootstrapOrder(ctx, params[0]); err != nil {
+		t.Fatalf("CreateBootstrapOrder failed: %v", err)
+	}
+	results, err := client.CreateBootstrapOrders(ctx, params, trenergy.WithWorkers(1), trenergy.WithCheckpoint(path))
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if r := results[0]; !r.Resumed || r.Attempts != 0 || r.Consumer == nil {
+		t.Errorf("expected item 0 to be found, got %+v", r)
+	}
+	if r := results[1]; r.Resumed || r.Attempts != 1 || r.Consumer == nil {
+		t.Errorf("expected item 1 to be created, got %+v", r)
+	}
+	if n := len(fake.Consumers()); n != len(params) {
+		t.Errorf("expected %d consumers, got %d", len(params), n)
+	}
+}
@@ -4,12 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"net/url"
-	"strings"
 )
 
 const (
@@ -24,6 +21,14 @@ type Client struct {
 	httpClient *http.Client
 	apiKey     string
 	headers    http.Header
+	// checkStatus turns "status": false responses into a *BusinessError.
+	checkStatus bool
+	retry       RetryPolicy
+	limiter     *rateLimiter
+	consumers   *consumerCache
+	prices      *priceCache
+	budget      *budgetGuard
+	notifier    OrderNotifier
 }
 
 // Option serves as a functional option for configuring the Client.
@@ -52,6 +57,14 @@ func WithHeader(key, value string) Option {
 	}
 }
 
+// WithStatusCheck controls whether responses with "status": false are
+// returned as a *BusinessError. It is enabled by default.
+func WithStatusCheck(enabled bool) Option {
+	return func(c *Client) {
+		c.checkStatus = enabled
+	}
+}
+
 // WithTestNet enables the testnet environment.
 func WithTestNet() Option {
 	return func(c *Client) {
@@ -68,10 +81,13 @@ func WithTestNet() Option {
 func NewClient(apiKey string, opts ...Option) *Client {
 	u, _ := url.Parse(defaultBaseURL)
 	c := &Client{
-		baseURL:    u,
-		httpClient: http.DefaultClient,
-		apiKey:     apiKey,
-		headers:    make(http.Header),
+		baseURL:     u,
+		httpClient:  http.DefaultClient,
+		apiKey:      apiKey,
+		headers:     make(http.Header),
+		checkStatus: true,
+		consumers:   newConsumerCache(),
+		prices:      newPriceCache(),
 	}
 
 	for _, opt := range opts {
@@ -86,49 +102,32 @@ func NewClient(apiKey string, opts ...Option) *Client {
 	return c
 }
 
-// NewRequest creates an HTTP request.
+// NewRequest creates an HTTP request with a JSON-encoded body.
 func (c *Client) NewRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	return c.buildRequest(ctx, method, path, bodyJSON, body)
+}
+
+// buildRequest creates an HTTP request, encoding body (if any) with enc.
+// The body is held in memory so that the request can be replayed on retry.
+func (c *Client) buildRequest(ctx context.Context, method, path string, enc bodyEncoding, body interface{}) (*http.Request, error) {
 	rel, err := url.Parse(path)
 	if err != nil {
 		return nil, err
 	}
 	u := c.baseURL.ResolveReference(rel)
 
-	var buf io.ReadWriter
+	var reader io.Reader
 	var contentType string
-
 	if body != nil {
-		// handle multipart/form-data if needed, for now assuming JSON or form-urlencoded based on samples
-		// actually samples show both JSON and FormData.
-		// Let's check typical usage. Most creation endpoints seem to use FormData.
-		// For simplicity, let's implement checking if body implements a specific interface or just default to JSON
-		// But wait, samples used `form-data` for creating consumers.
-		// We can handle this by checking if the body is `url.Values` for form-urlencoded or a map/struct for JSON.
-		// Or helper methods can handle serialization.
-		// Let's default to JSON here and allow helper methods to pass already encoded reader if needed?
-		// Actually, standard is to handle JSON serialization here.
-		// If we encounter form-data endpoints, we might need a separate way to signal that.
-
-		// For now, let's stick to JSON default, and if we need form-data we can handle it in specific methods or support it here.
-		// Given the `samples.json` shows strict key-value pairs in form-data, let's look at `consumer.go` later.
-		// Actually, `samples.json` shows requests with `Content-Type: application/json` for some GETs? No, GETs don't have body.
-		// POSTs in samples:
-		// consumer create: form-data
-		// consumer activate: no body?
-		// consumer mass trx: form-data
-		// It seems MANY write operations use form-data.
-
-		// Let's implement a "Form" helper or just treat `body` as JSON unless it is of a specific type.
-
-		buf = new(bytes.Buffer)
-		err := json.NewEncoder(buf).Encode(body)
+		b, ct, err := encodeBody(enc, body)
 		if err != nil {
 			return nil, err
 		}
-		contentType = "application/json"
+		reader = bytes.NewReader(b)
+		contentType = ct
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, u.String(), buf)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
 	if err != nil {
 		return nil, err
 	}
@@ -146,27 +145,39 @@ func (c *Client) NewRequest(ctx context.Context, method, path string, body inter
 
 // Do executes the request and decodes the response into v.
 func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doWithRetry(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		// Try to decode error response
-		// For now just return fmt.Errorf
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return resp, fmt.Errorf("API error: %s (status: %d) body: %s", resp.Status, resp.StatusCode, string(bodyBytes))
+		apiErr := newAPIError(resp.StatusCode, bodyBytes)
+		apiErr.Header = resp.Header
+		return resp, apiErr
 	}
 
-	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
-		} else {
-			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
-				return resp, err
-			}
-		}
+	if v == nil {
+		return resp, nil
+	}
+	if w, ok := v.(io.Writer); ok {
+		_, err = io.Copy(w, resp.Body)
+		return resp, err
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(bodyBytes, v); err != nil {
+		return resp, err
+	}
+
+	if sr, ok := v.(statusReporter); ok && c.checkStatus && !sr.apiStatus() {
+		bizErr := &BusinessError{APIError: *newAPIError(resp.StatusCode, bodyBytes)}
+		bizErr.Header = resp.Header
+		return resp, bizErr
 	}
 
 	return resp, nil
@@ -182,57 +193,13 @@ func (c *Client) sendRequest(ctx context.Context, method, path string, body inte
 	return err
 }
 
-// Separate helper for form-data if needed.
-func (c *Client) postForm(ctx context.Context, path string, data url.Values, v interface{}) error {
-	rel, err := url.Parse(path)
-	if err != nil {
-		return err
-	}
-	u := c.baseURL.ResolveReference(rel)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), strings.NewReader(data.Encode()))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	for k, v := range c.headers {
-		req.Header[k] = v
-	}
-
-	_, err = c.Do(req, v)
-	return err
-}
-
-// Helper for multipart form data
-func (c *Client) postMultipart(ctx context.Context, path string, fields map[string]string, v interface{}) error {
-	rel, err := url.Parse(path)
+// Helper for sending form bodies, either urlencoded or multipart.
+// body is a struct with `form` tags, see encodeForm.
+func (c *Client) sendForm(ctx context.Context, method, path string, enc bodyEncoding, body interface{}, v interface{}) error {
+	req, err := c.buildRequest(ctx, method, path, enc, body)
 	if err != nil {
 		return err
 	}
-	u := c.baseURL.ResolveReference(rel)
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	for key, val := range fields {
-		_ = writer.WriteField(key, val)
-	}
-
-	err = writer.Close()
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), body)
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	for k, v := range c.headers {
-		req.Header[k] = v
-	}
-
 	_, err = c.Do(req, v)
 	return err
 }
@@ -17,14 +17,14 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Printf("User: %s, Balance: %f\n", account.Data.Name, account.Data.Balance)
+	fmt.Printf("User: %s, Balance: %s TRX\n", account.Data.Name, account.Data.Balance)
 	// Activate Address
 	bootstrapOrder, err := client.CreateBootstrapOrder(context.Background(), trenergy.ConsumerParams{
 		Address:        "TCsKjXeT652tbDhjVzVdFEtacXNwhBCcDQ",
-		PaymentPeriod:  15,
+		PaymentPeriod:  trenergy.PaymentPeriod15M,
 		AutoRenewal:    false,
 		ResourceAmount: 65150,
-		Resource:       1, // 0 - BANDWIDTH, 1 - ENERGY
+		Resource:       trenergy.ResourceEnergy,
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -1,82 +1,112 @@
 package trenergy
 
 import (
-	"bytes"
+	"cmp"
 	"context"
 	"fmt"
-	"mime/multipart"
-	"net/http"
-	"net/url"
-	"strconv"
-	"strings"
+	"time"
 )
 
 // Consumer represents a consumer entity.
 type Consumer struct {
-	ID                    int         `json:"id"`
-	Name                  string      `json:"name"`
-	Address               string      `json:"address"`
-	Resource              string      `json:"resource"`
-	ResourceAmount        interface{} `json:"resource_amount"`         // Can be string or int based on samples
-	DesiredResourceAmount interface{} `json:"desired_resource_amount"` // Can be string
-	CreationType          int         `json:"creation_type"`
-	PaymentPeriod         int         `json:"payment_period"`
-	AutoRenewal           bool        `json:"auto_renewal"`
-	IsActive              bool        `json:"is_active"`
-	Order                 *Order      `json:"order"`
-	WebhookURL            string      `json:"webhook_url"`
-	CreatedAt             string      `json:"created_at"`
-	UpdatedAt             string      `json:"updated_at"`
-	EstimatedCostTrx      float64     `json:"estimated_cost_trx,omitempty"`
+	ID                    int           `json:"id"`
+	Name                  string        `json:"name"`
+	Address               string        `json:"address"`
+	Resource              Resource      `json:"resource"`
+	ResourceAmount        FlexInt       `json:"resource_amount"`
+	DesiredResourceAmount FlexInt       `json:"desired_resource_amount"`
+	CreationType          int           `json:"creation_type"`
+	PaymentPeriod         PaymentPeriod `json:"payment_period"`
+	AutoRenewal           bool          `json:"auto_renewal"`
+	IsActive              bool          `json:"is_active"`
+	Order                 *Order        `json:"order"`
+	WebhookURL            string        `json:"webhook_url"`
+	CreatedAt             Timestamp     `json:"created_at"`
+	UpdatedAt             Timestamp     `json:"updated_at"`
+	EstimatedCostTrx      TRX           `json:"estimated_cost_trx,omitempty"`
 }
 
 type Order struct {
-	Status               int    `json:"status"`
-	CompletionPercentage int    `json:"completion_percentage"`
-	CreatedAt            string `json:"created_at"`
-	UpdatedAt            string `json:"updated_at"`
-	ValidUntil           string `json:"valid_until"`
+	Status               OrderStatus `json:"status"`
+	CompletionPercentage int         `json:"completion_percentage"`
+	CreatedAt            Timestamp   `json:"created_at"`
+	UpdatedAt            Timestamp   `json:"updated_at"`
+	ValidUntil           Timestamp   `json:"valid_until"`
 }
 
-// ConsumerParams are parameters for creating/updating a consumer.
+// ExpiresIn returns the time left until ValidUntil. It is negative once the
+// order has expired, and zero if ValidUntil is unknown.
+func (o *Order) ExpiresIn() time.Duration {
+	if o.ValidUntil.IsZero() {
+		return 0
+	}
+	return time.Until(o.ValidUntil.Time)
+}
+
+// IsExpired reports whether the order is no longer valid at now.
+func (o *Order) IsExpired(now time.Time) bool {
+	return !o.ValidUntil.IsZero() && !now.Before(o.ValidUntil.Time)
+}
+
+// ConsumerParams are parameters for creating a consumer.
 type ConsumerParams struct {
-	Address        string
-	PaymentPeriod  int // usage: 15, 60, etc.
-	AutoRenewal    bool
-	ResourceAmount int64
-	Name           string
-	Resource       int // 0 - BANDWIDTH, 1 - ENERGY
-	WebhookURL     string
+	Address        string        `form:"address"`
+	PaymentPeriod  PaymentPeriod `form:"payment_period"`
+	AutoRenewal    bool          `form:"auto_renewal"`
+	ResourceAmount int64         `form:"resource_amount"`
+	Name           string        `form:"name,omitempty"`
+	Resource       Resource      `form:"resource"`
+	WebhookURL     string        `form:"webhook_url,omitempty"`
+}
+
+// Validate checks the params of a new order.
+func (p ConsumerParams) Validate() error {
+	if err := ValidateAddress(p.Address); err != nil {
+		return err
+	}
+	if !p.Resource.Valid() {
+		return fmt.Errorf("%w: unknown resource %d", ErrInvalidParams, p.Resource)
+	}
+	if !p.PaymentPeriod.Valid() {
+		return fmt.Errorf("%w: %w: %d", ErrInvalidParams, ErrInvalidPaymentPeriod, p.PaymentPeriod)
+	}
+	if p.ResourceAmount <= 0 {
+		return fmt.Errorf("%w: resource amount must be positive", ErrInvalidParams)
+	}
+	return nil
 }
 
 // CreateBootstrapOrder creates a new consumer order.
 func (c *Client) CreateBootstrapOrder(ctx context.Context, params ConsumerParams) (*APIResponse[*Consumer], error) {
-	// Note: samples show form-data
-	data := make(map[string]string)
-	data["address"] = params.Address
-	data["payment_period"] = strconv.Itoa(params.PaymentPeriod)
-	if params.AutoRenewal {
-		data["auto_renewal"] = "1"
-	} else {
-		data["auto_renewal"] = "0"
+	if err := params.Validate(); err != nil {
+		return nil, err
 	}
-	data["resource_amount"] = strconv.FormatInt(params.ResourceAmount, 10)
-
-	if params.Name != "" {
-		data["name"] = params.Name
+	var cost TRX
+	if c.budget != nil {
+		prices, err := c.priceTables(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, cost, err = orderCost(prices, params); err != nil {
+			return nil, err
+		}
 	}
-	// Resource default is Energy (1), optional? Sample sends "1".
-	data["resource"] = strconv.Itoa(params.Resource)
-
-	if params.WebhookURL != "" {
-		data["webhook_url"] = params.WebhookURL
+	spend, err := c.reserve(ctx, "bootstrap order", cost, params.PaymentPeriod.Duration())
+	if err != nil {
+		return nil, err
 	}
-
+	// Note: samples show form-data
 	var resp APIResponse[*Consumer]
-	err := c.postMultipart(ctx, "/api/consumers/bootstrap-order", data, &resp)
+	err = c.sendForm(ctx, "POST", "/api/consumers/bootstrap-order", bodyMultipart, params, &resp)
 	if err != nil {
+		spend.fail(ctx, err)
 		return nil, err
 	}
+	if resp.Data != nil {
+		c.consumers.put(*resp.Data)
+		cost = cmp.Or(resp.Data.EstimatedCostTrx, cost)
+	}
+	spend.commit(ctx, cost)
 	return &resp, nil
 }
 
@@ -93,6 +123,33 @@ func (c *Client) ListConsumers(ctx context.Context, page int) (*APIResponse[[]Co
 	return &resp, nil
 }
 
+// ListConsumersParams filters and sorts the consumer list. Nil pointers leave
+// the corresponding filter unset.
+type ListConsumersParams struct {
+	Page          int       `form:"page,omitempty"`
+	PerPage       int       `form:"per_page,omitempty"`
+	Address       string    `form:"address,omitempty"`
+	IsActive      *bool     `form:"is_active"`
+	Resource      *Resource `form:"resource"`
+	SortBy        string    `form:"sort_by,omitempty"`        // created_at/resource_amount
+	SortDirection string    `form:"sort_direction,omitempty"` // asc/desc
+}
+
+// FilterConsumers returns a page of consumers matching params.
+func (c *Client) FilterConsumers(ctx context.Context, params ListConsumersParams) (*APIResponse[[]Consumer], error) {
+	path, err := withQuery("/api/consumers", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp APIResponse[[]Consumer]
+	err = c.sendRequest(ctx, "GET", path, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 // GetConsumer retrieves a single consumer by ID.
 func (c *Client) GetConsumer(ctx context.Context, id int) (*APIResponse[*Consumer], error) {
 	path := fmt.Sprintf("/api/consumers/%d", id)
@@ -112,6 +169,7 @@ func (c *Client) ActivateConsumer(ctx context.Context, id int) (*APIResponse[str
 	if err != nil {
 		return nil, err
 	}
+	c.consumers.setActive(id, true)
 	return &resp, nil
 }
 
@@ -123,45 +181,53 @@ func (c *Client) DeactivateConsumer(ctx context.Context, id int) (*APIResponse[s
 	if err != nil {
 		return nil, err
 	}
+	c.consumers.setActive(id, false)
 	return &resp, nil
 }
 
-// UpdateConsumer updates a consumer.
-func (c *Client) UpdateConsumer(ctx context.Context, id int, params ConsumerParams) (*APIResponse[struct{}], error) {
-	path := fmt.Sprintf("/api/consumers/%d", id)
-	// Sample uses PATCH and form-urlencoded
-	data := url.Values{}
-	if params.Name != "" {
-		data.Set("name", params.Name)
-	}
-	// Add other fields if update allows them. Sample only shows name being updated, but docs might allow more.
-	// Assuming logic similar to create for other optional fields if documentation implies.
-	// For now implementing what is in samples (only name is shown in PATCH sample).
-
-	// BUT sample 801 also shows "resource_amount", "payment_period" disabled... implying they might be updateable or just visible in docs.
-	// Let's assume we can update what we can.
+// UpdateConsumerParams are the fields to change on a consumer. Nil fields are
+// left unchanged, so a zero value can be sent explicitly, e.g. an empty
+// WebhookURL removes the webhook.
+type UpdateConsumerParams struct {
+	Name           *string        `form:"name"`
+	ResourceAmount *int64         `form:"resource_amount"`
+	PaymentPeriod  *PaymentPeriod `form:"payment_period"`
+	AutoRenewal    *bool          `form:"auto_renewal"`
+	WebhookURL     *string        `form:"webhook_url"`
+}
 
-	var resp APIResponse[struct{}]
-	// We need a helper for PATCH with urlencoded.
-	// Since postForm is POST, we need something generic or just construct it here.
+// Validate checks the fields that are set.
+func (p UpdateConsumerParams) Validate() error {
+	if p.PaymentPeriod != nil && !p.PaymentPeriod.Valid() {
+		return fmt.Errorf("%w: %w: %d", ErrInvalidParams, ErrInvalidPaymentPeriod, *p.PaymentPeriod)
+	}
+	if p.ResourceAmount != nil && *p.ResourceAmount <= 0 {
+		return fmt.Errorf("%w: resource amount must be positive", ErrInvalidParams)
+	}
+	return nil
+}
 
-	// Let's reuse postForm logic but with PATCH
-	u, _ := url.Parse(path)
-	finalURL := c.baseURL.ResolveReference(u)
+// Ptr returns a pointer to v, for filling optional fields such as those of
+// UpdateConsumerParams.
+func Ptr[T any](v T) *T {
+	return &v
+}
 
-	req, err := http.NewRequestWithContext(ctx, "PATCH", finalURL.String(), strings.NewReader(data.Encode()))
-	if err != nil {
+// UpdateConsumer changes the fields of a consumer that are set in params and
+// returns the updated consumer.
+func (c *Client) UpdateConsumer(ctx context.Context, id int, params UpdateConsumerParams) (*APIResponse[*Consumer], error) {
+	if err := params.Validate(); err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	for k, v := range c.headers {
-		req.Header[k] = v
-	}
-
-	_, err = c.Do(req, &resp)
+	path := fmt.Sprintf("/api/consumers/%d", id)
+	var resp APIResponse[*Consumer]
+	err := c.sendForm(ctx, "PATCH", path, bodyURLEncoded, params, &resp)
 	if err != nil {
 		return nil, err
 	}
+	if resp.Data != nil {
+		c.consumers.put(*resp.Data)
+	}
 	return &resp, nil
 }
 
@@ -173,16 +239,17 @@ func (c *Client) DeleteConsumer(ctx context.Context, id int) (*APIResponse[struc
 	if err != nil {
 		return nil, err
 	}
+	c.consumers.forget(id)
 	return &resp, nil
 }
 
 // ConsumerPayment represents a payment history item.
 type ConsumerPayment struct {
-	Amount    float64     `json:"amount"`
-	Type      int         `json:"type"`
-	Coin      int         `json:"coin"`
-	Quantity  interface{} `json:"quantity"` // Can be int or null
-	CreatedAt string      `json:"created_at"`
+	Amount    TRX       `json:"amount"`
+	Type      TxType    `json:"type"`
+	Coin      Coin      `json:"coin"`
+	Quantity  FlexInt   `json:"quantity"`
+	CreatedAt Timestamp `json:"created_at"`
 }
 
 // GetConsumerPayments gets payments for a consumer.
@@ -201,15 +268,33 @@ type ActivateAddressData struct{}
 
 // ActivateAddress activates a generic address.
 func (c *Client) ActivateAddress(ctx context.Context, address string) (*APIResponse[ActivateAddressData], error) {
+	if err := ValidateAddress(address); err != nil {
+		return nil, err
+	}
+	var fee TRX
+	if c.budget != nil {
+		prices, err := c.priceTables(ctx)
+		if err != nil {
+			return nil, err
+		}
+		fee = prices.AddressActivationFee
+	}
+	spend, err := c.reserve(ctx, "address activation", fee, 0)
+	if err != nil {
+		return nil, err
+	}
 	// Sample 1150 POST /api/extra/activate-address with formdata
-	data := make(map[string]string)
-	data["address"] = address
+	form := struct {
+		Address string `form:"address"`
+	}{Address: address}
 
 	var resp APIResponse[ActivateAddressData]
-	err := c.postMultipart(ctx, "/api/extra/activate-address", data, &resp)
+	err = c.sendForm(ctx, "POST", "/api/extra/activate-address", bodyMultipart, form, &resp)
 	if err != nil {
+		spend.fail(ctx, err)
 		return nil, err
 	}
+	spend.commit(ctx, fee)
 	return &resp, nil
 }
 
@@ -225,12 +310,12 @@ type ConsumersSummary struct {
 	TotalBandwidthConsumption  int                `json:"total_bandwidth_consumption"`
 	ActiveEnergyConsumption    int                `json:"active_energy_consumption"`
 	ActiveBandwidthConsumption int                `json:"active_bandwidth_consumption"`
-	PeriodPricesEnergy         map[string]float64 `json:"period_prices_energy"` // keys are strings "15", "60" etc.
+	PeriodPricesEnergy         map[string]float64 `json:"period_prices_energy"` // SUN per unit, keyed by period in minutes, e.g. "15", "60".
 	PeriodPricesBandwidth      map[string]float64 `json:"period_prices_bandwidth"`
-	TrxTopUpFee                float64            `json:"trx_top_up_fee"`
-	AddressActivationFee       float64            `json:"address_activation_fee"`
+	TrxTopUpFee                TRX                `json:"trx_top_up_fee"`
+	AddressActivationFee       TRX                `json:"address_activation_fee"`
 	RechargePriceSun           float64            `json:"recharge_price_sun"`
-	DailyExpensesAvg           float64            `json:"daily_expenses_avg"`
+	DailyExpensesAvg           TRX                `json:"daily_expenses_avg"`
 }
 
 // GetConsumersSummary retrieves summary.
@@ -242,113 +327,3 @@ func (c *Client) GetConsumersSummary(ctx context.Context) (*APIResponse[*Consume
 	}
 	return &resp, nil
 }
-
-// MassPaymentPeriodParams
-type MassPaymentPeriodParams struct {
-	ConsumerIDs   []string // or int
-	PaymentPeriod int
-	AutoRenewal   bool
-}
-
-// MassPaymentPeriod updates payment period for multiple consumers.
-func (c *Client) MassPaymentPeriod(ctx context.Context, params MassPaymentPeriodParams) (*APIResponse[struct{}], error) {
-	// POST /api/consumers/mass/payment-period
-	// Samples show `consumer_ids[]="1"`.
-	// We need to support array values in form data.
-	// postMultipart simple helper doesn't support arrays cleanly, we might need a custom builder here or update helper.
-	// Let's do it manually here for correctness.
-
-	path := "/api/consumers/mass/payment-period"
-	rel, err := url.Parse(path)
-	if err != nil {
-		return nil, err
-	}
-	u := c.baseURL.ResolveReference(rel)
-
-	// We can use url.Values? No, sample uses form-data.
-	// If we use multipart:
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	for _, id := range params.ConsumerIDs {
-		writer.WriteField("consumer_ids[]", id)
-	}
-	writer.WriteField("payment_period", strconv.Itoa(params.PaymentPeriod))
-	renewal := "0"
-	if params.AutoRenewal {
-		renewal = "1"
-	}
-	writer.WriteField("auto_renewal", renewal)
-
-	err = writer.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), body)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	for k, v := range c.headers {
-		req.Header[k] = v
-	}
-
-	var resp APIResponse[struct{}]
-	_, err = c.Do(req, &resp)
-	if err != nil {
-		return nil, err
-	}
-	return &resp, nil
-}
-
-// MassTrxParams
-type MassTrxParams struct {
-	Consumers []string // Ids
-	Amount    float64
-}
-
-// MassTrx sends TRX to consumers.
-func (c *Client) MassTrx(ctx context.Context, params MassTrxParams) (*APIResponse[struct{}], error) {
-	// POST /api/consumers/mass/trx
-
-	// Same issue with array consumers[]
-
-	path := "/api/consumers/mass/trx"
-	rel, err := url.Parse(path)
-	if err != nil {
-		return nil, err
-	}
-	u := c.baseURL.ResolveReference(rel)
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	writer.WriteField("amount", fmt.Sprintf("%f", params.Amount))
-	for _, id := range params.Consumers {
-		writer.WriteField("consumers[]", id)
-	}
-
-	err = writer.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), body)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	for k, v := range c.headers {
-		req.Header[k] = v
-	}
-
-	var resp APIResponse[struct{}]
-	_, err = c.Do(req, &resp)
-	if err != nil {
-		return nil, err
-	}
-	return &resp, nil
-}
@@ -0,0 +1,247 @@
+package trenergy
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// bodyEncoding selects how a request body is serialized.
+type bodyEncoding int
+
+const (
+	bodyJSON bodyEncoding = iota
+	bodyURLEncoded
+	bodyMultipart
+)
+
+// formField is a single key/value pair of an encoded form.
+type formField struct {
+	key   string
+	value string
+}
+
+// encodeBody serializes body with the given encoding and returns the bytes
+// together with the matching Content-Type.
+func encodeBody(enc bodyEncoding, body any) ([]byte, string, error) {
+	switch enc {
+	case bodyJSON:
+		buf := new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(body); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "application/json", nil
+
+	case bodyURLEncoded:
+		fields, err := encodeForm(body)
+		if err != nil {
+			return nil, "", err
+		}
+		values := make(url.Values)
+		for _, f := range fields {
+			values.Add(f.key, f.value)
+		}
+		return []byte(values.Encode()), "application/x-www-form-urlencoded", nil
+
+	case bodyMultipart:
+		fields, err := encodeForm(body)
+		if err != nil {
+			return nil, "", err
+		}
+		buf := new(bytes.Buffer)
+		writer := multipart.NewWriter(buf)
+		for _, f := range fields {
+			if err := writer.WriteField(f.key, f.value); err != nil {
+				return nil, "", err
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), writer.FormDataContentType(), nil
+	}
+	return nil, "", fmt.Errorf("trenergy: unknown body encoding %d", enc)
+}
+
+// withQuery appends the form encoding of params to the query of path.
+func withQuery(path string, params any) (string, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	fields, err := encodeForm(params)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for _, f := range fields {
+		q.Add(f.key, f.value)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// encodeForm flattens v into form fields. v may be a url.Values or a struct
+// (or pointer to struct) whose fields carry `form:"name[,omitempty]"` tags.
+// Untagged fields are skipped, except embedded structs which are flattened.
+//
+// Values are formatted as follows: bools as "0"/"1", floats in the shortest
+// decimal form, encoding.TextMarshaler through MarshalText, and slices as
+// one field per element under the same name (e.g. `form:"consumer_ids[]"`).
+// Nil pointers are always omitted.
+func encodeForm(v any) ([]formField, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if values, ok := v.(url.Values); ok {
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var fields []formField
+		for _, k := range keys {
+			for _, val := range values[k] {
+				fields = append(fields, formField{k, val})
+			}
+		}
+		return fields, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("trenergy: cannot form-encode %T", v)
+	}
+	if !rv.CanAddr() {
+		// Copy so that pointer-receiver TextMarshalers are found.
+		addr := reflect.New(rv.Type()).Elem()
+		addr.Set(rv)
+		rv = addr
+	}
+
+	var fields []formField
+	err := appendStruct(&fields, rv)
+	return fields, err
+}
+
+func appendStruct(fields *[]formField, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+
+		tag, hasTag := sf.Tag.Lookup("form")
+		if !hasTag {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				if err := appendStruct(fields, fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !sf.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		omitEmpty := opts == "omitempty"
+
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		if (fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array) && !isTextMarshaler(fv) {
+			if omitEmpty && fv.Len() == 0 {
+				continue
+			}
+			for j := 0; j < fv.Len(); j++ {
+				s, err := formatValue(fv.Index(j))
+				if err != nil {
+					return fmt.Errorf("trenergy: field %s: %w", sf.Name, err)
+				}
+				*fields = append(*fields, formField{name, s})
+			}
+			continue
+		}
+
+		s, err := formatValue(fv)
+		if err != nil {
+			return fmt.Errorf("trenergy: field %s: %w", sf.Name, err)
+		}
+		*fields = append(*fields, formField{name, s})
+	}
+	return nil
+}
+
+func isTextMarshaler(v reflect.Value) bool {
+	_, ok := textMarshaler(v)
+	return ok
+}
+
+// textMarshaler returns v as an encoding.TextMarshaler, trying its address
+// for pointer-receiver implementations.
+func textMarshaler(v reflect.Value) (encoding.TextMarshaler, bool) {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		return m, true
+	}
+	if v.CanAddr() {
+		m, ok := v.Addr().Interface().(encoding.TextMarshaler)
+		return m, ok
+	}
+	return nil, false
+}
+
+// formatValue renders a scalar as a form value.
+func formatValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if m, ok := textMarshaler(v); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
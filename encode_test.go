@@ -0,0 +1,92 @@
+package trenergy
+
+import (
+	"bytes"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type formTestParams struct {
+	IDs     []int    `form:"consumer_ids[],omitempty"`
+	Amount  float64  `form:"amount"`
+	Renewal bool     `form:"auto_renewal"`
+	Name    string   `form:"name,omitempty"`
+	Limit   *int     `form:"limit"`
+	Tags    []string `form:"tags[],omitempty"`
+	Ignored string
+}
+
+func TestEncodeForm(t *testing.T) {
+	limit := 0
+	fields, err := encodeForm(formTestParams{
+		IDs:     []int{3, 1},
+		Amount:  1.000001,
+		Renewal: true,
+		Limit:   &limit,
+		Ignored: "x",
+	})
+	if err != nil {
+		t.Fatalf("encodeForm failed: %v", err)
+	}
+	want := []formField{
+		{"consumer_ids[]", "3"},
+		{"consumer_ids[]", "1"},
+		{"amount", "1.000001"},
+		{"auto_renewal", "1"},
+		{"limit", "0"},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("got %v, want %v", fields, want)
+	}
+}
+
+func TestEncodeBodyURLEncoded(t *testing.T) {
+	b, ct, err := encodeBody(bodyURLEncoded, formTestParams{Amount: 0.5, Name: "a b"})
+	if err != nil {
+		t.Fatalf("encodeBody failed: %v", err)
+	}
+	if ct != "application/x-www-form-urlencoded" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	got, _ := url.ParseQuery(string(b))
+	want := url.Values{"amount": {"0.5"}, "auto_renewal": {"0"}, "name": {"a b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEncodeBodyMultipart(t *testing.T) {
+	b, ct, err := encodeBody(bodyMultipart, formTestParams{IDs: []int{7, 8}, Amount: 10})
+	if err != nil {
+		t.Fatalf("encodeBody failed: %v", err)
+	}
+	_, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		t.Fatalf("bad content type %q: %v", ct, err)
+	}
+	form, err := multipart.NewReader(bytes.NewReader(b), params["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("ReadForm failed: %v", err)
+	}
+	want := map[string][]string{
+		"consumer_ids[]": {"7", "8"},
+		"amount":         {"10"},
+		"auto_renewal":   {"0"},
+	}
+	if !reflect.DeepEqual(form.Value, want) {
+		t.Errorf("got %v, want %v", form.Value, want)
+	}
+}
+
+func TestWithQuery(t *testing.T) {
+	path, err := withQuery("/api/transactions/internal", InternalTransactionParams{Page: 2, Types: []TxType{TxTypeTopUp, TxTypeActivation}})
+	if err != nil {
+		t.Fatalf("withQuery failed: %v", err)
+	}
+	if want := "/api/transactions/internal?page=2&types%5B%5D=1&types%5B%5D=3"; path != want {
+		t.Errorf("got %q, want %q", path, want)
+	}
+}
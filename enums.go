@@ -0,0 +1,238 @@
+package trenergy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resource is the kind of TRON resource a consumer receives.
+type Resource int
+
+// Resource codes as documented for the resource field of orders.
+const (
+	ResourceBandwidth Resource = 0
+	ResourceEnergy    Resource = 1
+)
+
+var resourceNames = map[Resource]string{
+	ResourceBandwidth: "bandwidth",
+	ResourceEnergy:    "energy",
+}
+
+// String returns "energy" or "bandwidth".
+func (r Resource) String() string {
+	return enumString(r, resourceNames)
+}
+
+// Valid reports whether r is a known resource.
+func (r Resource) Valid() bool {
+	_, ok := resourceNames[r]
+	return ok
+}
+
+// MarshalJSON encodes r by name, as the API does in responses.
+func (r Resource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON accepts either the numeric or the string form.
+func (r *Resource) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, r, resourceNames)
+}
+
+// PaymentPeriod is the rental period of a consumer order, in minutes. The
+// named periods are the common ones; the periods an account can actually
+// order are the keys of ConsumersSummary.PeriodPricesEnergy.
+type PaymentPeriod int
+
+const (
+	PaymentPeriod15M PaymentPeriod = 15
+	PaymentPeriod1H  PaymentPeriod = 60
+	PaymentPeriod1D  PaymentPeriod = 1440
+)
+
+var paymentPeriodNames = map[PaymentPeriod]string{
+	PaymentPeriod15M: "15m",
+	PaymentPeriod1H:  "1h",
+	PaymentPeriod1D:  "1d",
+}
+
+// String returns a short form such as "15m" or "1h", or the number of
+// minutes, e.g. "30m", for other periods.
+func (p PaymentPeriod) String() string {
+	if name, ok := paymentPeriodNames[p]; ok {
+		return name
+	}
+	return strconv.Itoa(int(p)) + "m"
+}
+
+// Valid reports whether p is positive. Whether the API offers the period is
+// only known from its price tables, so other periods are left to the server.
+func (p PaymentPeriod) Valid() bool {
+	return p > 0
+}
+
+// Duration returns the period as a time.Duration.
+func (p PaymentPeriod) Duration() time.Duration {
+	return time.Duration(p) * time.Minute
+}
+
+// UnmarshalJSON accepts either the numeric or the string form.
+func (p *PaymentPeriod) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, p, paymentPeriodNames)
+}
+
+// OrderStatus is the state of a consumer order.
+//
+// The API documentation does not list the status codes, so the values below
+// are unconfirmed. Unknown codes decode as their number.
+type OrderStatus int
+
+const (
+	OrderStatusPending    OrderStatus = 0
+	OrderStatusProcessing OrderStatus = 1
+	OrderStatusCompleted  OrderStatus = 2
+	OrderStatusExpired    OrderStatus = 3
+	OrderStatusFailed     OrderStatus = 4
+)
+
+var orderStatusNames = map[OrderStatus]string{
+	OrderStatusPending:    "pending",
+	OrderStatusProcessing: "processing",
+	OrderStatusCompleted:  "completed",
+	OrderStatusExpired:    "expired",
+	OrderStatusFailed:     "failed",
+}
+
+// String returns the status name, e.g. "completed".
+func (s OrderStatus) String() string {
+	return enumString(s, orderStatusNames)
+}
+
+// Valid reports whether s is a known order status.
+func (s OrderStatus) Valid() bool {
+	_, ok := orderStatusNames[s]
+	return ok
+}
+
+// UnmarshalJSON accepts either the numeric or the string form.
+func (s *OrderStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, s, orderStatusNames)
+}
+
+// TxType is the type of an internal transaction or consumer payment.
+//
+// The API documentation does not list the type codes and the values below
+// are unconfirmed; check them against your own transaction history before
+// filtering on them. Unknown codes decode as their number.
+type TxType int
+
+const (
+	TxTypeTopUp        TxType = 1
+	TxTypeOrderPayment TxType = 2
+	TxTypeActivation   TxType = 3
+	TxTypeMassTrx      TxType = 4
+	TxTypeStake        TxType = 5
+	TxTypeUnstake      TxType = 6
+	TxTypeWithdrawal   TxType = 7
+)
+
+var txTypeNames = map[TxType]string{
+	TxTypeTopUp:        "top_up",
+	TxTypeOrderPayment: "order_payment",
+	TxTypeActivation:   "activation",
+	TxTypeMassTrx:      "mass_trx",
+	TxTypeStake:        "stake",
+	TxTypeUnstake:      "unstake",
+	TxTypeWithdrawal:   "withdrawal",
+}
+
+// String returns the type name, e.g. "order_payment".
+func (t TxType) String() string {
+	return enumString(t, txTypeNames)
+}
+
+// Valid reports whether t is a known transaction type.
+func (t TxType) Valid() bool {
+	_, ok := txTypeNames[t]
+	return ok
+}
+
+// UnmarshalJSON accepts either the numeric or the string form.
+func (t *TxType) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, t, txTypeNames)
+}
+
+// Coin is the balance an internal transaction is booked against. CoinTRX (1)
+// is the main balance in the API samples; CoinEnergy (3) is unconfirmed.
+type Coin int
+
+const (
+	CoinTRX    Coin = 1
+	CoinEnergy Coin = 3
+)
+
+var coinNames = map[Coin]string{
+	CoinTRX:    "trx",
+	CoinEnergy: "energy",
+}
+
+// String returns the coin name, e.g. "trx".
+func (c Coin) String() string {
+	return enumString(c, coinNames)
+}
+
+// Valid reports whether c is a known coin.
+func (c Coin) Valid() bool {
+	_, ok := coinNames[c]
+	return ok
+}
+
+// UnmarshalJSON accepts either the numeric or the string form.
+func (c *Coin) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, c, coinNames)
+}
+
+// enumString returns the name of v, or its number if it is unknown.
+func enumString[T ~int](v T, names map[T]string) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return strconv.Itoa(int(v))
+}
+
+// unmarshalEnum decodes a JSON number, numeric string or case-insensitive
+// name into v. Unknown numbers are kept so that new server values do not
+// break decoding; unknown names are an error. null leaves v unchanged.
+func unmarshalEnum[T ~int](data []byte, v *T, names map[T]string) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var s string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	} else {
+		s = string(data)
+	}
+	s = strings.TrimSpace(s)
+
+	if n, err := strconv.Atoi(s); err == nil {
+		*v = T(n)
+		return nil
+	}
+	for k, name := range names {
+		if strings.EqualFold(name, s) {
+			*v = k
+			return nil
+		}
+	}
+	return fmt.Errorf("trenergy: unknown %T value %q", *v, s)
+}
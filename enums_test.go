@@ -0,0 +1,48 @@
+package trenergy_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+)
+
+func TestEnumJSON(t *testing.T) {
+	var c trenergy.Consumer
+	if err := json.Unmarshal([]byte(`{"resource":"ENERGY","payment_period":"60","order":{"status":2}}`), &c); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if c.Resource != trenergy.ResourceEnergy || c.PaymentPeriod != trenergy.PaymentPeriod1H || c.Order.Status != trenergy.OrderStatusCompleted {
+		t.Errorf("unexpected decode %v %v %v", c.Resource, c.PaymentPeriod, c.Order.Status)
+	}
+
+	var r trenergy.Resource
+	if err := json.Unmarshal([]byte(`0`), &r); err != nil || r != trenergy.ResourceBandwidth {
+		t.Errorf("expected bandwidth, got %v (%v)", r, err)
+	}
+	if err := json.Unmarshal([]byte(`"water"`), &r); err == nil {
+		t.Error("expected error for unknown resource name")
+	}
+
+	var tx trenergy.TxType
+	if err := json.Unmarshal([]byte(`"order_payment"`), &tx); err != nil || tx != trenergy.TxTypeOrderPayment {
+		t.Errorf("expected order payment, got %v (%v)", tx, err)
+	}
+	if err := json.Unmarshal([]byte(`99`), &tx); err != nil || tx.Valid() || tx.String() != "99" {
+		t.Errorf("expected unknown type 99 to be kept, got %v (%v)", tx, err)
+	}
+
+	b, _ := json.Marshal(trenergy.ResourceEnergy)
+	if string(b) != `"energy"` {
+		t.Errorf("unexpected resource encoding %s", b)
+	}
+}
+
+func TestPaymentPeriodValid(t *testing.T) {
+	if p := trenergy.PaymentPeriod(30); !p.Valid() || p.String() != "30m" {
+		t.Errorf("expected 30 minutes to be valid, got %v %s", p.Valid(), p)
+	}
+	if trenergy.PaymentPeriod(0).Valid() || trenergy.PaymentPeriod(-15).Valid() {
+		t.Error("expected non-positive periods to be invalid")
+	}
+}
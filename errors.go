@@ -0,0 +1,150 @@
+package trenergy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Sentinel errors that can be matched against an *APIError with errors.Is.
+var (
+	ErrUnauthorized         = errors.New("trenergy: unauthorized")
+	ErrNotFound             = errors.New("trenergy: not found")
+	ErrRateLimited          = errors.New("trenergy: rate limited")
+	ErrInsufficientFunds    = errors.New("trenergy: insufficient funds")
+	ErrInvalidPaymentPeriod = errors.New("trenergy: invalid payment period")
+)
+
+// ErrInvalidParams is returned when request parameters fail client-side
+// validation, before anything is sent.
+var ErrInvalidParams = errors.New("trenergy: invalid parameters")
+
+// APIError is returned when the API answers with an HTTP status >= 400.
+type APIError struct {
+	StatusCode int
+	Message    string
+	// Errors holds per-field validation messages, keyed by field name.
+	Errors map[string][]string
+	// Body is the raw response body.
+	Body []byte
+	// Header holds the response headers, e.g. Retry-After.
+	Header http.Header
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "API error (status: %d)", e.StatusCode)
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	} else if len(e.Body) > 0 {
+		b.WriteString(" body: ")
+		b.Write(e.Body)
+	}
+
+	fields := make([]string, 0, len(e.Errors))
+	for field := range e.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		fmt.Fprintf(&b, "; %s: %s", field, strings.Join(e.Errors[field], ", "))
+	}
+	return b.String()
+}
+
+// Is reports whether the error matches one of the package sentinel errors.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrInsufficientFunds:
+		return e.mentions("not enough funds", "insufficient balance", "insufficient funds")
+	case ErrInvalidPaymentPeriod:
+		if _, ok := e.Errors["payment_period"]; ok {
+			return true
+		}
+		return e.mentions("payment_period is invalid", "payment period is invalid")
+	}
+	return false
+}
+
+// mentions reports whether the message or any field error contains one of the phrases.
+func (e *APIError) mentions(phrases ...string) bool {
+	texts := []string{e.Message}
+	for _, msgs := range e.Errors {
+		texts = append(texts, msgs...)
+	}
+	for _, text := range texts {
+		text = strings.ToLower(text)
+		for _, p := range phrases {
+			if strings.Contains(text, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BusinessError is returned when the API answers with a successful HTTP status
+// but reports "status": false in the body.
+type BusinessError struct {
+	APIError
+}
+
+// Error implements the error interface.
+func (e *BusinessError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("API business error (status: %d) body: %s", e.StatusCode, e.Body)
+	}
+	return "API business error: " + e.Message
+}
+
+// Unwrap exposes the underlying *APIError to errors.As.
+func (e *BusinessError) Unwrap() error {
+	return &e.APIError
+}
+
+// errorResponse is the body shape of failed API calls.
+type errorResponse struct {
+	Message string                     `json:"message"`
+	Errors  map[string]json.RawMessage `json:"errors"`
+}
+
+// newAPIError builds an *APIError from a failed response.
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Body:       body,
+	}
+
+	var er errorResponse
+	if err := json.Unmarshal(body, &er); err != nil {
+		return apiErr
+	}
+	apiErr.Message = er.Message
+
+	if len(er.Errors) > 0 {
+		apiErr.Errors = make(map[string][]string, len(er.Errors))
+		for field, raw := range er.Errors {
+			var msgs []string
+			if err := json.Unmarshal(raw, &msgs); err != nil {
+				var msg string
+				if err := json.Unmarshal(raw, &msg); err != nil {
+					continue
+				}
+				msgs = []string{msg}
+			}
+			apiErr.Errors[field] = msgs
+		}
+	}
+	return apiErr
+}
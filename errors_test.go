@@ -0,0 +1,83 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+)
+
+func TestAPIErrorSentinels(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"message":"Unauthenticated."}`, trenergy.ErrUnauthorized},
+		{"not found", http.StatusNotFound, `{"message":"Not found"}`, trenergy.ErrNotFound},
+		{"rate limited", http.StatusTooManyRequests, `Too Many Attempts.`, trenergy.ErrRateLimited},
+		{"funds", http.StatusUnprocessableEntity, `{"status":false,"message":"Not enough funds"}`, trenergy.ErrInsufficientFunds},
+		{"payment period", http.StatusUnprocessableEntity, `{"message":"The given data was invalid.","errors":{"payment_period":["The selected payment_period is invalid."]}}`, trenergy.ErrInvalidPaymentPeriod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+			_, err := client.GetAccountInfo(context.Background())
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+
+			var apiErr *trenergy.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *APIError, got %T", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, apiErr.StatusCode)
+			}
+			if string(apiErr.Body) != tt.body {
+				t.Errorf("expected raw body %q, got %q", tt.body, apiErr.Body)
+			}
+		})
+	}
+}
+
+func TestStatusFalseIsBusinessError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":false,"message":"Not enough funds","data":null}`))
+	}))
+	defer srv.Close()
+
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+	_, err := client.ActivateAddress(context.Background(), "TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF")
+
+	var bizErr *trenergy.BusinessError
+	if !errors.As(err, &bizErr) {
+		t.Fatalf("expected *BusinessError, got %v", err)
+	}
+	if bizErr.Message != "Not enough funds" {
+		t.Errorf("unexpected message %q", bizErr.Message)
+	}
+	if !errors.Is(err, trenergy.ErrInsufficientFunds) {
+		t.Error("expected ErrInsufficientFunds to match")
+	}
+
+	lenient := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL), trenergy.WithStatusCheck(false))
+	resp, err := lenient.ActivateAddress(context.Background(), "TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF")
+	if err != nil {
+		t.Fatalf("expected no error with status check disabled, got %v", err)
+	}
+	if resp.Status {
+		t.Error("expected status false")
+	}
+}
@@ -0,0 +1,200 @@
+package estimate
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cyvadra/trenergy"
+)
+
+// Public TRON HTTP API endpoints.
+const (
+	MainnetURL = "https://api.trongrid.io"
+	NileURL    = "https://nile.trongrid.io"
+)
+
+// ChainBackend is the part of a TRON node the estimator needs.
+type ChainBackend interface {
+	// GetAccountResources returns the energy and bandwidth of an account.
+	GetAccountResources(ctx context.Context, address trenergy.Address) (*AccountResources, error)
+	// TriggerConstantContract runs a contract call without broadcasting it.
+	TriggerConstantContract(ctx context.Context, call ContractCall) (*ConstantResult, error)
+}
+
+// AccountResources are the energy and bandwidth limits of an account and how
+// much of them is used. Limits come from staking, except FreeNetLimit, the
+// daily free bandwidth.
+type AccountResources struct {
+	EnergyLimit  int64 `json:"EnergyLimit"`
+	EnergyUsed   int64 `json:"EnergyUsed"`
+	FreeNetLimit int64 `json:"freeNetLimit"`
+	FreeNetUsed  int64 `json:"freeNetUsed"`
+	NetLimit     int64 `json:"NetLimit"`
+	NetUsed      int64 `json:"NetUsed"`
+}
+
+// AvailableEnergy returns the energy the account can spend now.
+func (r *AccountResources) AvailableEnergy() int64 {
+	return max(r.EnergyLimit-r.EnergyUsed, 0)
+}
+
+// AvailableBandwidth returns the free and staked bandwidth the account can
+// spend now.
+func (r *AccountResources) AvailableBandwidth() int64 {
+	return max(r.FreeNetLimit-r.FreeNetUsed, 0) + max(r.NetLimit-r.NetUsed, 0)
+}
+
+// ContractCall is a call of a contract function.
+type ContractCall struct {
+	Owner    trenergy.Address
+	Contract trenergy.Address
+	// Selector is the function signature, e.g. "transfer(address,uint256)".
+	Selector string
+	// Parameter is the hex encoded ABI arguments.
+	Parameter string
+}
+
+// ConstantResult is the outcome of TriggerConstantContract.
+type ConstantResult struct {
+	EnergyUsed int64
+	// Result is the data returned by the call.
+	Result []byte
+	// Reverted reports whether the call reverted, e.g. a transfer exceeding
+	// the sender's balance.
+	Reverted bool
+}
+
+// HTTPBackend is a ChainBackend using the HTTP API of a TRON full node, such
+// as TronGrid.
+type HTTPBackend struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+}
+
+// HTTPOption configures an HTTPBackend.
+type HTTPOption func(*HTTPBackend)
+
+// WithHTTPClient sets the HTTP client. The default is http.DefaultClient.
+func WithHTTPClient(c *http.Client) HTTPOption {
+	return func(b *HTTPBackend) {
+		b.httpClient = c
+	}
+}
+
+// WithAPIKey sets the TronGrid API key, sent as TRON-PRO-API-KEY.
+func WithAPIKey(key string) HTTPOption {
+	return func(b *HTTPBackend) {
+		b.apiKey = key
+	}
+}
+
+// NewHTTPBackend returns an HTTPBackend for the node at baseURL, e.g. MainnetURL.
+func NewHTTPBackend(baseURL string, opts ...HTTPOption) *HTTPBackend {
+	b := &HTTPBackend{baseURL: strings.TrimRight(baseURL, "/"), httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+func (b *HTTPBackend) GetAccountResources(ctx context.Context, address trenergy.Address) (*AccountResources, error) {
+	req := map[string]any{"address": address.String(), "visible": true}
+	var res AccountResources
+	if err := b.post(ctx, "/wallet/getaccountresource", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (b *HTTPBackend) TriggerConstantContract(ctx context.Context, call ContractCall) (*ConstantResult, error) {
+	req := map[string]any{
+		"owner_address":     call.Owner.String(),
+		"contract_address":  call.Contract.String(),
+		"function_selector": call.Selector,
+		"parameter":         call.Parameter,
+		"visible":           true,
+	}
+	var res struct {
+		Result struct {
+			Result  bool   `json:"result"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"result"`
+		EnergyUsed     int64    `json:"energy_used"`
+		ConstantResult []string `json:"constant_result"`
+		Transaction    struct {
+			Ret []struct {
+				Ret string `json:"ret"`
+			} `json:"ret"`
+		} `json:"transaction"`
+	}
+	if err := b.post(ctx, "/wallet/triggerconstantcontract", req, &res); err != nil {
+		return nil, err
+	}
+	if !res.Result.Result {
+		msg := res.Result.Message
+		if decoded, err := hex.DecodeString(msg); err == nil {
+			msg = string(decoded)
+		}
+		return nil, fmt.Errorf("estimate: %s: %s %s", call.Selector, res.Result.Code, msg)
+	}
+
+	out := &ConstantResult{EnergyUsed: res.EnergyUsed}
+	if len(res.ConstantResult) > 0 {
+		data, err := hex.DecodeString(res.ConstantResult[0])
+		if err != nil {
+			return nil, fmt.Errorf("estimate: decoding result of %s: %w", call.Selector, err)
+		}
+		out.Result = data
+	}
+	for _, r := range res.Transaction.Ret {
+		if r.Ret == "FAILED" || r.Ret == "REVERT" {
+			out.Reverted = true
+		}
+	}
+	return out, nil
+}
+
+func (b *HTTPBackend) post(ctx context.Context, path string, body, v any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if b.apiKey != "" {
+		req.Header.Set("TRON-PRO-API-KEY", b.apiKey)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("estimate: %s: status %d: %s", path, resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+	// Nodes report some failures as {"Error": "..."} with status 200.
+	var nodeErr struct {
+		Error string `json:"Error"`
+	}
+	if json.Unmarshal(respBody, &nodeErr) == nil && nodeErr.Error != "" {
+		return fmt.Errorf("estimate: %s: %s", path, nodeErr.Error)
+	}
+	return json.Unmarshal(respBody, v)
+}
@@ -0,0 +1,235 @@
+// Package estimate works out how much energy and bandwidth to rent for a
+// TRC20 transfer, such as sending USDT.
+//
+// The energy a transfer needs depends mostly on whether the recipient already
+// holds the token: writing a new balance slot costs about twice as much. The
+// estimator simulates the transfer on a TRON node, falls back to typical
+// figures when the simulation reverts, and subtracts what the sender already
+// has:
+//
+//	est := estimate.NewEnergyEstimator(estimate.NewHTTPBackend(estimate.MainnetURL))
+//	e, err := est.EstimateTransfer(ctx, estimate.Transfer{From: from, Contract: usdt, To: to})
+//	if err == nil && e.EnergyToRent > 0 {
+//		_, err = client.CreateBootstrapOrder(ctx, e.EnergyParams(trenergy.PaymentPeriod15M))
+//	}
+package estimate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/cyvadra/trenergy"
+)
+
+// Typical figures for a USDT transfer, used when the transfer cannot be
+// simulated.
+const (
+	// EnergyExistingHolder is the energy of a transfer to an address that
+	// already holds the token.
+	EnergyExistingHolder int64 = 65_000
+	// EnergyNewHolder is the energy of a transfer to an address that does not.
+	EnergyNewHolder int64 = 131_000
+	// TransferBandwidth is the size in bytes, and so the bandwidth, of a
+	// signed TRC20 transfer transaction.
+	TransferBandwidth int64 = 345
+)
+
+const (
+	transferSelector  = "transfer(address,uint256)"
+	balanceOfSelector = "balanceOf(address)"
+)
+
+// Fallback is why an Estimate uses the typical figures instead of a
+// simulation.
+type Fallback string
+
+const (
+	// FallbackReverted means the simulated transfer reverted, usually because
+	// the sender does not hold enough of the token yet.
+	FallbackReverted Fallback = "simulation reverted"
+	// FallbackNoEnergy means the node reported no energy for the simulation.
+	FallbackNoEnergy Fallback = "simulation reported no energy"
+)
+
+// Transfer describes a TRC20 transfer.
+type Transfer struct {
+	From     trenergy.Address
+	Contract trenergy.Address
+	To       trenergy.Address
+	// Amount is in the token's smallest unit. Nil simulates a transfer of one
+	// unit, which costs the same energy as any other nonzero amount.
+	Amount *big.Int
+}
+
+// Estimate is the energy and bandwidth a transfer needs and how much of it
+// has to be rented.
+type Estimate struct {
+	Sender trenergy.Address
+	// Energy and Bandwidth are what the transfer consumes.
+	Energy    int64
+	Bandwidth int64
+	// RecipientHoldsToken reports whether the recipient had a nonzero balance.
+	RecipientHoldsToken bool
+	// Simulated reports whether Energy comes from simulating the transfer
+	// rather than from the typical figures.
+	Simulated bool
+	// Fallback is why the typical figures were used. It is empty when
+	// Simulated is true.
+	Fallback Fallback
+
+	AvailableEnergy    int64
+	AvailableBandwidth int64
+	// EnergyToRent and BandwidthToRent are what the sender lacks. Zero means
+	// nothing needs to be rented.
+	EnergyToRent    int64
+	BandwidthToRent int64
+}
+
+// EnergyParams returns the parameters of an order renting EnergyToRent to the
+// sender for period.
+func (e *Estimate) EnergyParams(period trenergy.PaymentPeriod) trenergy.ConsumerParams {
+	return trenergy.ConsumerParams{
+		Address:        e.Sender.String(),
+		PaymentPeriod:  period,
+		ResourceAmount: e.EnergyToRent,
+		Resource:       trenergy.ResourceEnergy,
+	}
+}
+
+// BandwidthParams returns the parameters of an order renting BandwidthToRent
+// to the sender for period.
+func (e *Estimate) BandwidthParams(period trenergy.PaymentPeriod) trenergy.ConsumerParams {
+	return trenergy.ConsumerParams{
+		Address:        e.Sender.String(),
+		PaymentPeriod:  period,
+		ResourceAmount: e.BandwidthToRent,
+		Resource:       trenergy.ResourceBandwidth,
+	}
+}
+
+// EnergyEstimator estimates the resources of TRC20 transfers.
+type EnergyEstimator struct {
+	backend   ChainBackend
+	margin    float64
+	holder    int64
+	newHolder int64
+	ignoreOwn bool
+}
+
+// Option configures an EnergyEstimator.
+type Option func(*EnergyEstimator)
+
+// WithMargin adds a fraction to the simulated energy, e.g. 0.1 for 10%, to
+// allow for the state changing before the transfer is sent. The default is 0.
+func WithMargin(m float64) Option {
+	return func(e *EnergyEstimator) {
+		e.margin = m
+	}
+}
+
+// WithFallbackEnergy sets the energy assumed when the transfer cannot be
+// simulated. The defaults are EnergyExistingHolder and EnergyNewHolder.
+func WithFallbackEnergy(existingHolder, newHolder int64) Option {
+	return func(e *EnergyEstimator) {
+		e.holder = existingHolder
+		e.newHolder = newHolder
+	}
+}
+
+// WithoutSenderResources ignores the sender's own energy and bandwidth, so
+// that the whole requirement is rented.
+func WithoutSenderResources() Option {
+	return func(e *EnergyEstimator) {
+		e.ignoreOwn = true
+	}
+}
+
+// NewEnergyEstimator returns an estimator using backend.
+func NewEnergyEstimator(backend ChainBackend, opts ...Option) *EnergyEstimator {
+	e := &EnergyEstimator{
+		backend:   backend,
+		holder:    EnergyExistingHolder,
+		newHolder: EnergyNewHolder,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// EstimateTransfer estimates the resources of t and how many the sender has
+// to rent. A simulation that reverts, for example because the sender does not
+// hold enough of the token yet, or that reports no energy is replaced by the
+// typical figures, and Estimate.Fallback says why. Errors of the backend are
+// returned.
+func (e *EnergyEstimator) EstimateTransfer(ctx context.Context, t Transfer) (*Estimate, error) {
+	amount := t.Amount
+	if amount == nil || amount.Sign() <= 0 {
+		amount = big.NewInt(1)
+	}
+
+	balance, err := e.backend.TriggerConstantContract(ctx, ContractCall{
+		Owner:     t.From,
+		Contract:  t.Contract,
+		Selector:  balanceOfSelector,
+		Parameter: encodeAddress(t.To),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("estimate: recipient balance: %w", err)
+	}
+	est := &Estimate{
+		Sender:              t.From,
+		Bandwidth:           TransferBandwidth,
+		RecipientHoldsToken: new(big.Int).SetBytes(balance.Result).Sign() > 0,
+	}
+
+	sim, err := e.backend.TriggerConstantContract(ctx, ContractCall{
+		Owner:     t.From,
+		Contract:  t.Contract,
+		Selector:  transferSelector,
+		Parameter: encodeAddress(t.To) + encodeUint(amount),
+	})
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("estimate: simulating transfer: %w", err)
+	case sim.Reverted:
+		est.Fallback = FallbackReverted
+	case sim.EnergyUsed <= 0:
+		est.Fallback = FallbackNoEnergy
+	default:
+		est.Energy = int64(math.Ceil(float64(sim.EnergyUsed) * (1 + e.margin)))
+		est.Simulated = true
+	}
+	if !est.Simulated {
+		est.Energy = e.newHolder
+		if est.RecipientHoldsToken {
+			est.Energy = e.holder
+		}
+	}
+
+	if !e.ignoreOwn {
+		res, err := e.backend.GetAccountResources(ctx, t.From)
+		if err != nil {
+			return nil, fmt.Errorf("estimate: sender resources: %w", err)
+		}
+		est.AvailableEnergy = res.AvailableEnergy()
+		est.AvailableBandwidth = res.AvailableBandwidth()
+	}
+	est.EnergyToRent = max(est.Energy-est.AvailableEnergy, 0)
+	est.BandwidthToRent = max(est.Bandwidth-est.AvailableBandwidth, 0)
+	return est, nil
+}
+
+// encodeAddress ABI encodes an address argument: the 20 bytes after the
+// prefix, left padded to 32 bytes.
+func encodeAddress(a trenergy.Address) string {
+	return strings.Repeat("0", 24) + a.Hex()[2:]
+}
+
+// encodeUint ABI encodes a uint256 argument.
+func encodeUint(n *big.Int) string {
+	return fmt.Sprintf("%064x", n)
+}
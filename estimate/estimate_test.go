@@ -0,0 +1,126 @@
+package estimate_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/estimate"
+)
+
+var (
+	usdt      = trenergy.MustParseAddress("TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
+	sender    = trenergy.MustParseAddress("TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF")
+	recipient = trenergy.MustParseAddress("TCsKjXeT652tbDhjVzVdFEtacXNwhBCcDQ")
+)
+
+func TestEstimateTransfer(t *testing.T) {
+	ctx := context.Background()
+	backend := estimate.NewFakeBackend()
+	backend.SetTokenBalance(usdt, sender, big.NewInt(5_000_000))
+	est := estimate.NewEnergyEstimator(backend)
+	transfer := estimate.Transfer{From: sender, Contract: usdt, To: recipient, Amount: big.NewInt(1_000_000)}
+
+	e, err := est.EstimateTransfer(ctx, transfer)
+	if err != nil {
+		t.Fatalf("EstimateTransfer failed: %v", err)
+	}
+	if e.RecipientHoldsToken || !e.Simulated || e.Energy != 130_285 || e.EnergyToRent != e.Energy {
+		t.Errorf("unexpected estimate for a new holder %+v", e)
+	}
+	if e.AvailableBandwidth != 600 || e.BandwidthToRent != 0 {
+		t.Errorf("expected free bandwidth to cover the transfer, got %+v", e)
+	}
+	params := e.EnergyParams(trenergy.PaymentPeriod15M)
+	if err := params.Validate(); err != nil || params.Address != sender.String() {
+		t.Errorf("unexpected params %+v: %v", params, err)
+	}
+
+	backend.SetTokenBalance(usdt, recipient, big.NewInt(1))
+	backend.SetResources(sender, estimate.AccountResources{EnergyLimit: 50_000, EnergyUsed: 10_000})
+	e, err = est.EstimateTransfer(ctx, transfer)
+	if err != nil {
+		t.Fatalf("EstimateTransfer failed: %v", err)
+	}
+	if !e.RecipientHoldsToken || e.Energy != 64_285 || e.EnergyToRent != 64_285-40_000 {
+		t.Errorf("unexpected estimate for an existing holder %+v", e)
+	}
+	if e.BandwidthToRent != estimate.TransferBandwidth {
+		t.Errorf("expected the bandwidth to be rented, got %+v", e)
+	}
+}
+
+func TestEstimateTransferFallback(t *testing.T) {
+	backend := estimate.NewFakeBackend()
+	est := estimate.NewEnergyEstimator(backend, estimate.WithoutSenderResources())
+
+	// The sender holds no tokens, so the simulated transfer reverts.
+	e, err := est.EstimateTransfer(context.Background(), estimate.Transfer{From: sender, Contract: usdt, To: recipient})
+	if err != nil {
+		t.Fatalf("EstimateTransfer failed: %v", err)
+	}
+	if e.Simulated || e.Fallback != estimate.FallbackReverted || e.Energy != estimate.EnergyNewHolder || e.EnergyToRent != estimate.EnergyNewHolder {
+		t.Errorf("unexpected estimate %+v", e)
+	}
+	if n := len(backend.Calls()); n != 2 {
+		t.Errorf("expected two contract calls, got %d", n)
+	}
+}
+
+func TestHTTPBackend(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("TRON-PRO-API-KEY") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/wallet/getaccountresource":
+			fmt.Fprint(w, `{"freeNetLimit":600,"freeNetUsed":100,"EnergyLimit":1000}`)
+		case "/wallet/triggerconstantcontract":
+			param, _ := body["parameter"].(string)
+			if body["function_selector"] == "balanceOf(address)" && strings.HasSuffix(param, recipient.Hex()[2:]) {
+				fmt.Fprintf(w, `{"result":{"result":true},"energy_used":535,"constant_result":["%064x"]}`, 7)
+				return
+			}
+			fmt.Fprint(w, `{"result":{"result":true},"energy_used":64285,"constant_result":[""],"transaction":{"ret":[{}]}}`)
+		}
+	}))
+	defer srv.Close()
+
+	backend := estimate.NewHTTPBackend(srv.URL, estimate.WithAPIKey("key"))
+	e, err := estimate.NewEnergyEstimator(backend, estimate.WithMargin(0.1)).EstimateTransfer(context.Background(),
+		estimate.Transfer{From: sender, Contract: usdt, To: recipient})
+	if err != nil {
+		t.Fatalf("EstimateTransfer failed: %v", err)
+	}
+	if !e.RecipientHoldsToken || e.Energy != 70_714 || e.AvailableEnergy != 1000 || e.AvailableBandwidth != 500 {
+		t.Errorf("unexpected estimate %+v", e)
+	}
+}
+
+func TestEstimateTransferSimulationError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["function_selector"] == "balanceOf(address)" {
+			fmt.Fprint(w, `{"result":{"result":true},"energy_used":535,"constant_result":[""]}`)
+			return
+		}
+		fmt.Fprint(w, `{"result":{"code":"OTHER_ERROR","message":"node overloaded"}}`)
+	}))
+	defer srv.Close()
+
+	est := estimate.NewEnergyEstimator(estimate.NewHTTPBackend(srv.URL), estimate.WithoutSenderResources())
+	_, err := est.EstimateTransfer(context.Background(), estimate.Transfer{From: sender, Contract: usdt, To: recipient})
+	if err == nil || !strings.Contains(err.Error(), "node overloaded") {
+		t.Errorf("expected the simulation error, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package trenergy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FlexInt is an integer that the API may send as a number, a numeric string
+// or null. Valid is false when the value was null or absent.
+type FlexInt struct {
+	Value int64
+	Valid bool
+}
+
+// NewFlexInt returns a valid FlexInt holding v.
+func NewFlexInt(v int64) FlexInt {
+	return FlexInt{Value: v, Valid: true}
+}
+
+// String returns the decimal value, or "null".
+func (f FlexInt) String() string {
+	if !f.Valid {
+		return "null"
+	}
+	return strconv.FormatInt(f.Value, 10)
+}
+
+// MarshalJSON encodes f as a JSON number, or null.
+func (f FlexInt) MarshalJSON() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalJSON accepts a number, a numeric string, "" or null.
+func (f *FlexInt) UnmarshalJSON(data []byte) error {
+	s, ok, err := flexString(data)
+	if err != nil || !ok {
+		*f = FlexInt{}
+		return err
+	}
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*f = NewFlexInt(v)
+		return nil
+	}
+	// Integral values are sometimes sent with a fraction, e.g. "65000.0".
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v != float64(int64(v)) {
+		return fmt.Errorf("trenergy: cannot decode %s into FlexInt", data)
+	}
+	*f = NewFlexInt(int64(v))
+	return nil
+}
+
+// FlexNumber is a decimal number that the API may send as a number, a
+// numeric string or null. Valid is false when the value was null or absent.
+type FlexNumber struct {
+	Value float64
+	Valid bool
+}
+
+// NewFlexNumber returns a valid FlexNumber holding v.
+func NewFlexNumber(v float64) FlexNumber {
+	return FlexNumber{Value: v, Valid: true}
+}
+
+// String returns the shortest decimal form of the value, or "null".
+func (f FlexNumber) String() string {
+	if !f.Valid {
+		return "null"
+	}
+	return strconv.FormatFloat(f.Value, 'f', -1, 64)
+}
+
+// MarshalJSON encodes f as a JSON number, or null.
+func (f FlexNumber) MarshalJSON() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalJSON accepts a number, a numeric string, "" or null.
+func (f *FlexNumber) UnmarshalJSON(data []byte) error {
+	s, ok, err := flexString(data)
+	if err != nil || !ok {
+		*f = FlexNumber{}
+		return err
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("trenergy: cannot decode %s into FlexNumber", data)
+	}
+	*f = NewFlexNumber(v)
+	return nil
+}
+
+// flexString returns the textual value of a JSON number or string. ok is
+// false for null and empty strings.
+func flexString(data []byte) (s string, ok bool, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return "", false, nil
+	}
+	if data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return "", false, err
+		}
+		s = strings.TrimSpace(s)
+		return s, s != "", nil
+	}
+	return string(data), true, nil
+}
@@ -0,0 +1,53 @@
+package trenergy_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+)
+
+func TestFlexInt(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  trenergy.FlexInt
+		round string
+	}{
+		{`65000`, trenergy.NewFlexInt(65000), `65000`},
+		{`"65000"`, trenergy.NewFlexInt(65000), `65000`},
+		{`"65000.0"`, trenergy.NewFlexInt(65000), `65000`},
+		{`null`, trenergy.FlexInt{}, `null`},
+		{`""`, trenergy.FlexInt{}, `null`},
+	}
+	for _, tt := range tests {
+		var got trenergy.FlexInt
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.in, got, tt.want)
+		}
+		if b, _ := json.Marshal(got); string(b) != tt.round {
+			t.Errorf("%s: re-encoded as %s, want %s", tt.in, b, tt.round)
+		}
+	}
+
+	var bad trenergy.FlexInt
+	if err := json.Unmarshal([]byte(`"1.5"`), &bad); err == nil {
+		t.Error("expected error for fractional FlexInt")
+	}
+}
+
+func TestFlexNumber(t *testing.T) {
+	var tx trenergy.InternalTransaction
+	if err := json.Unmarshal([]byte(`{"instant_balance":"12.5"}`), &tx); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !tx.InstantBalance.Valid || tx.InstantBalance.Value != 12.5 {
+		t.Errorf("unexpected value %+v", tx.InstantBalance)
+	}
+	if err := json.Unmarshal([]byte(`{"instant_balance":null}`), &tx); err != nil || tx.InstantBalance.Valid {
+		t.Errorf("expected null to be kept, got %+v (%v)", tx.InstantBalance, err)
+	}
+}
@@ -0,0 +1,188 @@
+package trenergy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultConsumerCacheTTL is how long FindConsumerByAddress trusts its cache.
+const defaultConsumerCacheTTL = time.Minute
+
+// WithConsumerCacheTTL sets how long FindConsumerByAddress reuses a lookup,
+// including the negative result of a full scan. Zero disables the cache.
+func WithConsumerCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.consumers.ttl = ttl
+	}
+}
+
+// FindConsumerByAddress returns the consumer for address, or an error
+// wrapping ErrNotFound. If several consumers share the address, the first one
+// listed by the API is returned.
+//
+// The address filter of the consumer list is used when the API honours it.
+// Otherwise every consumer is listed once and indexed by address; the index
+// is reused for the cache TTL (see WithConsumerCacheTTL) and kept up to date
+// by CreateBootstrapOrder, UpdateConsumer, ActivateConsumer,
+// DeactivateConsumer and DeleteConsumer on the same Client.
+func (c *Client) FindConsumerByAddress(ctx context.Context, address string) (*Consumer, error) {
+	addr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	key := addr.String()
+	cache := c.consumers
+
+	if found, ok := cache.get(key); ok {
+		return found, nil
+	}
+
+	if !cache.filterUnsupported() {
+		resp, err := c.FilterConsumers(ctx, ListConsumersParams{Address: key})
+		if err != nil {
+			return nil, err
+		}
+		if allMatch(resp.Data, key) {
+			if len(resp.Data) == 0 {
+				return nil, fmt.Errorf("%w: no consumer for address %s", ErrNotFound, key)
+			}
+			cache.put(resp.Data[0])
+			return &resp.Data[0], nil
+		}
+		cache.setFilterUnsupported()
+	}
+
+	if !cache.scannedRecently() {
+		all, err := CollectAll(c.AllConsumers(ctx), 0)
+		if err != nil {
+			return nil, err
+		}
+		cache.replace(all)
+	}
+	if found, ok := cache.get(key); ok {
+		return found, nil
+	}
+	return nil, fmt.Errorf("%w: no consumer for address %s", ErrNotFound, key)
+}
+
+// allMatch reports whether every consumer has the address key, i.e. whether
+// the server applied the address filter.
+func allMatch(consumers []Consumer, key string) bool {
+	for _, c := range consumers {
+		if canonicalAddress(c.Address) != key {
+			return false
+		}
+	}
+	return true
+}
+
+func canonicalAddress(s string) string {
+	if a, err := ParseAddress(s); err == nil {
+		return a.String()
+	}
+	return s
+}
+
+// consumerCache indexes consumers by canonical address.
+type consumerCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	now       func() time.Time
+	byAddress map[string]cachedConsumer
+	scannedAt time.Time
+	noFilter  bool
+}
+
+type cachedConsumer struct {
+	consumer Consumer
+	at       time.Time
+}
+
+func newConsumerCache() *consumerCache {
+	return &consumerCache{
+		ttl:       defaultConsumerCacheTTL,
+		now:       time.Now,
+		byAddress: make(map[string]cachedConsumer),
+	}
+}
+
+func (cc *consumerCache) get(key string) (*Consumer, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	e, ok := cc.byAddress[key]
+	if !ok || cc.now().Sub(e.at) >= cc.ttl {
+		return nil, false
+	}
+	found := e.consumer
+	return &found, true
+}
+
+func (cc *consumerCache) put(c Consumer) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	key := canonicalAddress(c.Address)
+	if e, ok := cc.byAddress[key]; ok && e.consumer.ID != c.ID && cc.now().Sub(e.at) < cc.ttl {
+		// Keep the consumer listed first.
+		return
+	}
+	cc.byAddress[key] = cachedConsumer{consumer: c, at: cc.now()}
+}
+
+// forget drops the consumer with the given ID.
+func (cc *consumerCache) forget(id int) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for key, e := range cc.byAddress {
+		if e.consumer.ID == id {
+			delete(cc.byAddress, key)
+		}
+	}
+}
+
+// setActive updates the active state of the cached consumer with the given
+// ID. The entry is kept, so that a full scan still finds it.
+func (cc *consumerCache) setActive(id int, active bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for key, e := range cc.byAddress {
+		if e.consumer.ID == id {
+			e.consumer.IsActive = active
+			cc.byAddress[key] = e
+		}
+	}
+}
+
+// replace rebuilds the index from a full listing.
+func (cc *consumerCache) replace(all []Consumer) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	now := cc.now()
+	cc.byAddress = make(map[string]cachedConsumer, len(all))
+	for _, c := range all {
+		key := canonicalAddress(c.Address)
+		if _, ok := cc.byAddress[key]; !ok {
+			cc.byAddress[key] = cachedConsumer{consumer: c, at: now}
+		}
+	}
+	cc.scannedAt = now
+}
+
+func (cc *consumerCache) scannedRecently() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return !cc.scannedAt.IsZero() && cc.now().Sub(cc.scannedAt) < cc.ttl
+}
+
+func (cc *consumerCache) filterUnsupported() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.noFilter
+}
+
+func (cc *consumerCache) setFilterUnsupported() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.noFilter = true
+}
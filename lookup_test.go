@@ -0,0 +1,133 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/trenergytest"
+)
+
+const (
+	lookupAddressA = "TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF"
+	lookupAddressB = "TKzxdSv2FZKQrEqkKVgp5DcwEXBEKMg2Ax"
+)
+
+func TestFilterConsumers(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+	defer fake.Close()
+	client := fake.NewClient()
+	ctx := context.Background()
+
+	for _, addr := range []string{lookupAddressA, lookupAddressB} {
+		_, err := client.CreateBootstrapOrder(ctx, trenergy.ConsumerParams{
+			Address:        addr,
+			PaymentPeriod:  trenergy.PaymentPeriod15M,
+			ResourceAmount: 1000,
+			Resource:       trenergy.ResourceEnergy,
+		})
+		if err != nil {
+			t.Fatalf("CreateBootstrapOrder failed: %v", err)
+		}
+	}
+	id := fake.Consumers()[1].ID
+	if _, err := client.DeactivateConsumer(ctx, id); err != nil {
+		t.Fatalf("DeactivateConsumer failed: %v", err)
+	}
+
+	active := false
+	resp, err := client.FilterConsumers(ctx, trenergy.ListConsumersParams{IsActive: &active})
+	if err != nil {
+		t.Fatalf("FilterConsumers failed: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].ID != id {
+		t.Errorf("expected only consumer %d, got %+v", id, resp.Data)
+	}
+
+	resp, err = client.FilterConsumers(ctx, trenergy.ListConsumersParams{Address: lookupAddressA})
+	if err != nil {
+		t.Fatalf("FilterConsumers failed: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Address != lookupAddressA {
+		t.Errorf("expected consumer for %s, got %+v", lookupAddressA, resp.Data)
+	}
+
+	c, err := client.FindConsumerByAddress(ctx, lookupAddressB)
+	if err != nil {
+		t.Fatalf("FindConsumerByAddress failed: %v", err)
+	}
+	if c.ID != id || c.IsActive {
+		t.Errorf("expected inactive consumer %d, got %+v", id, c)
+	}
+
+	// Activating drops the cached consumer.
+	if _, err := client.ActivateConsumer(ctx, id); err != nil {
+		t.Fatalf("ActivateConsumer failed: %v", err)
+	}
+	if c, err := client.FindConsumerByAddress(ctx, lookupAddressB); err != nil || !c.IsActive {
+		t.Errorf("expected the consumer to be active, got %+v (%v)", c, err)
+	}
+}
+
+func TestFindConsumerByAddressFallsBackToScan(t *testing.T) {
+	var requests atomic.Int32
+	// The server ignores the address filter and always lists two pages.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			fmt.Fprint(w, `{"status":true}`)
+			return
+		}
+		requests.Add(1)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		addr := lookupAddressA
+		if page == 2 {
+			addr = lookupAddressB
+		}
+		fmt.Fprintf(w, `{"status":true,"data":[{"id":%d,"address":%q}],"meta":{"current_page":%d,"last_page":2}}`,
+			page, addr, max(page, 1))
+	}))
+	defer srv.Close()
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	c, err := client.FindConsumerByAddress(ctx, lookupAddressB)
+	if err != nil {
+		t.Fatalf("FindConsumerByAddress failed: %v", err)
+	}
+	if c.ID != 2 {
+		t.Errorf("expected consumer 2, got %d", c.ID)
+	}
+	// One filtered request, then a scan of two pages.
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	if _, err := client.FindConsumerByAddress(ctx, lookupAddressA); err != nil {
+		t.Fatalf("FindConsumerByAddress failed: %v", err)
+	}
+	_, err = client.FindConsumerByAddress(ctx, "TCsKjXeT652tbDhjVzVdFEtacXNwhBCcDQ")
+	if !errors.Is(err, trenergy.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected cached lookups, got %d requests", n)
+	}
+
+	// Deactivating keeps the consumer findable from the scanned index.
+	if _, err := client.DeactivateConsumer(ctx, 2); err != nil {
+		t.Fatalf("DeactivateConsumer failed: %v", err)
+	}
+	if c, err := client.FindConsumerByAddress(ctx, lookupAddressB); err != nil || c.ID != 2 || c.IsActive {
+		t.Errorf("expected inactive consumer 2, got %+v (%v)", c, err)
+	}
+
+	if _, err := client.FindConsumerByAddress(ctx, "not-an-address"); !errors.Is(err, trenergy.ErrInvalidAddress) {
+		t.Errorf("expected ErrInvalidAddress, got %v", err)
+	}
+}
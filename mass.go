@@ -0,0 +1,380 @@
+package trenergy
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// ErrNotApplied reports that a mass change was not applied to a consumer,
+// either because the server said so or because verification found the
+// consumer unchanged.
+var ErrNotApplied = errors.New("trenergy: change not applied")
+
+// MassOutcome is the result of a mass operation for one consumer.
+type MassOutcome struct {
+	ConsumerID int
+	// Consumer is the consumer as returned by the server or read back during
+	// verification, when available.
+	Consumer *Consumer
+	// Err is set when the change was not applied, or could not be confirmed.
+	Err error
+	// Verified reports whether the outcome was confirmed with a follow-up
+	// read rather than reported by the server.
+	Verified bool
+}
+
+// MassResult holds the outcome of a mass operation for each consumer, in the
+// order the IDs were given.
+type MassResult struct {
+	Outcomes []MassOutcome
+}
+
+// Failed returns the outcomes that have an error.
+func (r *MassResult) Failed() []MassOutcome {
+	var failed []MassOutcome
+	for _, o := range r.Outcomes {
+		if o.Err != nil {
+			failed = append(failed, o)
+		}
+	}
+	return failed
+}
+
+// Partial reports whether the change was applied to some consumers but not
+// all of them.
+func (r *MassResult) Partial() bool {
+	n := len(r.Failed())
+	return n > 0 && n < len(r.Outcomes)
+}
+
+// Err joins the errors of the failed outcomes, or returns nil.
+func (r *MassResult) Err() error {
+	var errs []error
+	for _, o := range r.Failed() {
+		errs = append(errs, fmt.Errorf("consumer %d: %w", o.ConsumerID, o.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// MassOption configures a mass operation.
+type MassOption func(*massConfig)
+
+type massConfig struct {
+	batchSize int
+	workers   int
+	verify    bool
+}
+
+// WithBatchSize sets how many consumers are sent per request. Larger inputs
+// are split into several requests. The API does not document a limit, so by
+// default all consumers are sent in one request; set a size if the server
+// rejects requests naming too many consumers.
+func WithBatchSize(n int) MassOption {
+	return func(c *massConfig) {
+		c.batchSize = n
+	}
+}
+
+// WithMassWorkers sets how many per-consumer requests, for verification and
+// for MassActivate, MassDeactivate and MassDelete, run concurrently. The
+// default is 4.
+func WithMassWorkers(n int) MassOption {
+	return func(c *massConfig) {
+		c.workers = n
+	}
+}
+
+// WithoutVerification trusts a successful mass request for the consumers the
+// server does not report on, instead of reading each of them back. It has no
+// effect on MassTrx, which is never verified.
+func WithoutVerification() MassOption {
+	return func(c *massConfig) {
+		c.verify = false
+	}
+}
+
+func newMassConfig(opts []MassOption) massConfig {
+	cfg := massConfig{workers: 4, verify: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.workers = max(cfg.workers, 1)
+	return cfg
+}
+
+// MassPaymentPeriodParams are the parameters of MassPaymentPeriod.
+type MassPaymentPeriodParams struct {
+	ConsumerIDs   []int         `form:"consumer_ids[]"`
+	PaymentPeriod PaymentPeriod `form:"payment_period"`
+	AutoRenewal   bool          `form:"auto_renewal"`
+}
+
+// MassPaymentPeriod sets the payment period and auto-renewal of several
+// consumers. Consumers the server does not report on are read back to check
+// that the change was applied.
+func (c *Client) MassPaymentPeriod(ctx context.Context, params MassPaymentPeriodParams, opts ...MassOption) (*MassResult, error) {
+	if len(params.ConsumerIDs) == 0 {
+		return nil, fmt.Errorf("%w: no consumers", ErrInvalidParams)
+	}
+	if !params.PaymentPeriod.Valid() {
+		return nil, fmt.Errorf("%w: %w: %d", ErrInvalidParams, ErrInvalidPaymentPeriod, params.PaymentPeriod)
+	}
+	send := func(ctx context.Context, ids []int) (json.RawMessage, error) {
+		// Samples show form-data with `consumer_ids[]="1"`.
+		chunk := params
+		chunk.ConsumerIDs = ids
+		var resp APIResponse[json.RawMessage]
+		err := c.sendForm(ctx, "POST", "/api/consumers/mass/payment-period", bodyMultipart, chunk, &resp)
+		return resp.Data, err
+	}
+	verify := func(ctx context.Context, id int) (*Consumer, error) {
+		resp, err := c.GetConsumer(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		consumer := resp.Data
+		if consumer == nil || consumer.PaymentPeriod != params.PaymentPeriod || consumer.AutoRenewal != params.AutoRenewal {
+			return consumer, ErrNotApplied
+		}
+		return consumer, nil
+	}
+	return c.runMass(ctx, params.ConsumerIDs, newMassConfig(opts), send, verify)
+}
+
+// MassTrxParams are the parameters of MassTrx.
+type MassTrxParams struct {
+	Consumers []int `form:"consumers[]"`
+	Amount    TRX   `form:"amount"`
+}
+
+// MassTrx sends Amount to each consumer.
+//
+// The API does not identify the payments a mass request makes, so outcomes
+// are not verified: consumers the server does not report on are taken as
+// paid when their request succeeds. A request that failed with a timeout or
+// a 5xx may still have paid its consumers; their outcomes carry the error,
+// and the Budget counts them as spent.
+func (c *Client) MassTrx(ctx context.Context, params MassTrxParams, opts ...MassOption) (*MassResult, error) {
+	if len(params.Consumers) == 0 {
+		return nil, fmt.Errorf("%w: no consumers", ErrInvalidParams)
+	}
+	if params.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidParams)
+	}
+	total := params.Amount * TRX(len(params.Consumers))
+	spend, err := c.reserve(ctx, "mass TRX", total, 0)
+	if err != nil {
+		return nil, err
+	}
+	send := func(ctx context.Context, ids []int) (json.RawMessage, error) {
+		chunk := params
+		chunk.Consumers = ids
+		var resp APIResponse[json.RawMessage]
+		err := c.sendForm(ctx, "POST", "/api/consumers/mass/trx", bodyMultipart, chunk, &resp)
+		return resp.Data, err
+	}
+	result, err := c.runMass(ctx, params.Consumers, newMassConfig(opts), send, nil)
+	paid := 0
+	for _, o := range result.Outcomes {
+		if o.Err == nil || !rejected(o.Err) {
+			paid++
+		}
+	}
+	spend.commit(ctx, params.Amount*TRX(paid))
+	return result, err
+}
+
+// MassActivate activates each consumer.
+func (c *Client) MassActivate(ctx context.Context, ids []int, opts ...MassOption) (*MassResult, error) {
+	return c.forEachConsumer(ctx, ids, newMassConfig(opts), func(ctx context.Context, id int) (*Consumer, error) {
+		_, err := c.ActivateConsumer(ctx, id)
+		return nil, err
+	})
+}
+
+// MassDeactivate deactivates each consumer.
+func (c *Client) MassDeactivate(ctx context.Context, ids []int, opts ...MassOption) (*MassResult, error) {
+	return c.forEachConsumer(ctx, ids, newMassConfig(opts), func(ctx context.Context, id int) (*Consumer, error) {
+		_, err := c.DeactivateConsumer(ctx, id)
+		return nil, err
+	})
+}
+
+// MassDelete deletes each consumer.
+func (c *Client) MassDelete(ctx context.Context, ids []int, opts ...MassOption) (*MassResult, error) {
+	return c.forEachConsumer(ctx, ids, newMassConfig(opts), func(ctx context.Context, id int) (*Consumer, error) {
+		_, err := c.DeleteConsumer(ctx, id)
+		return nil, err
+	})
+}
+
+// runMass sends ids in chunks. Outcomes the server reports are taken as is;
+// the others are verified, as are all consumers of a chunk whose request
+// failed in a way that may still have applied it. A nil verify disables
+// verification.
+func (c *Client) runMass(ctx context.Context, ids []int, cfg massConfig,
+	send func(context.Context, []int) (json.RawMessage, error),
+	verify func(context.Context, int) (*Consumer, error)) (*MassResult, error) {
+	outcomes := make(map[int]MassOutcome, len(ids))
+	cfg.verify = cfg.verify && verify != nil
+	size := cfg.batchSize
+	if size <= 0 {
+		size = len(ids)
+	}
+	var unknown []int
+	for chunk := range slices.Chunk(ids, size) {
+		data, err := send(ctx, chunk)
+		if err != nil {
+			if !cfg.verify || !transient(err) || ctx.Err() != nil {
+				for _, id := range chunk {
+					outcomes[id] = MassOutcome{ConsumerID: id, Err: err}
+				}
+				continue
+			}
+			unknown = append(unknown, chunk...)
+			continue
+		}
+
+		reported := decodeMassOutcomes(data)
+		for _, id := range chunk {
+			if o, ok := reported[id]; ok {
+				outcomes[id] = o
+			} else if cfg.verify {
+				unknown = append(unknown, id)
+			} else {
+				outcomes[id] = MassOutcome{ConsumerID: id}
+			}
+		}
+	}
+
+	if len(unknown) > 0 {
+		verified, _ := c.forEachConsumer(ctx, unknown, cfg, verify)
+		for _, o := range verified.Outcomes {
+			o.Verified = true
+			outcomes[o.ConsumerID] = o
+		}
+	}
+
+	result := &MassResult{Outcomes: make([]MassOutcome, len(ids))}
+	for i, id := range ids {
+		result.Outcomes[i] = outcomes[id]
+		if o := outcomes[id]; o.Consumer != nil {
+			c.consumers.put(*o.Consumer)
+		}
+	}
+	return result, result.Err()
+}
+
+// forEachConsumer calls fn for each id with a bounded number of workers.
+func (c *Client) forEachConsumer(ctx context.Context, ids []int, cfg massConfig,
+	fn func(context.Context, int) (*Consumer, error)) (*MassResult, error) {
+	result := &MassResult{Outcomes: make([]MassOutcome, len(ids))}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(cfg.workers, len(ids)) {
+		wg.Go(func() {
+			for i := range jobs {
+				consumer, err := fn(ctx, ids[i])
+				result.Outcomes[i] = MassOutcome{ConsumerID: ids[i], Consumer: consumer, Err: err}
+			}
+		})
+	}
+	for i := range ids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return result, result.Err()
+}
+
+// massItem is a per-consumer entry of a mass response. Entries may be plain
+// consumers or status objects.
+type massItem struct {
+	ID         FlexInt `json:"id"`
+	ConsumerID FlexInt `json:"consumer_id"`
+	Status     *bool   `json:"status"`
+	Success    *bool   `json:"success"`
+	Message    string  `json:"message"`
+	Error      string  `json:"error"`
+}
+
+// decodeMassOutcomes extracts per-consumer outcomes from the data of a mass
+// response. The data may be a list of entries, an object of entries keyed by
+// consumer ID, or anything else, in which case nothing is reported.
+func decodeMassOutcomes(data json.RawMessage) map[int]MassOutcome {
+	reported := make(map[int]MassOutcome)
+	data = bytes.TrimSpace(data)
+	var entries []json.RawMessage
+	var keyed map[string]json.RawMessage
+	switch {
+	case json.Unmarshal(data, &entries) == nil:
+		for _, raw := range entries {
+			if o, ok := decodeMassOutcome(0, raw); ok {
+				reported[o.ConsumerID] = o
+			}
+		}
+	case json.Unmarshal(data, &keyed) == nil:
+		for key, raw := range keyed {
+			id, err := strconv.Atoi(key)
+			if err != nil {
+				continue
+			}
+			if o, ok := decodeMassOutcome(id, raw); ok {
+				reported[id] = o
+			}
+		}
+	}
+	return reported
+}
+
+// decodeMassOutcome decodes one entry, for consumer id if it is known from
+// the enclosing object.
+func decodeMassOutcome(id int, raw json.RawMessage) (MassOutcome, bool) {
+	var ok bool
+	if json.Unmarshal(raw, &ok) == nil {
+		o := MassOutcome{ConsumerID: id}
+		if !ok {
+			o.Err = ErrNotApplied
+		}
+		return o, id != 0
+	}
+
+	var item massItem
+	if json.Unmarshal(raw, &item) != nil {
+		return MassOutcome{}, false
+	}
+	switch {
+	case item.ConsumerID.Valid:
+		id = int(item.ConsumerID.Value)
+	case id == 0 && item.ID.Valid:
+		id = int(item.ID.Value)
+	}
+	if id == 0 {
+		return MassOutcome{}, false
+	}
+
+	o := MassOutcome{ConsumerID: id}
+	var consumer Consumer
+	if json.Unmarshal(raw, &consumer) == nil && consumer.ID == id && consumer.Address != "" {
+		o.Consumer = &consumer
+	}
+	failed := item.Error != "" ||
+		(item.Status != nil && !*item.Status) ||
+		(item.Success != nil && !*item.Success)
+	if failed {
+		msg := cmp.Or(item.Error, item.Message)
+		if msg == "" {
+			o.Err = ErrNotApplied
+		} else {
+			o.Err = fmt.Errorf("%w: %s", ErrNotApplied, msg)
+		}
+	}
+	return o, true
+}
@@ -0,0 +1,173 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/trenergytest"
+)
+
+// massSetup creates a consumer for each of bulkAddresses and returns their IDs.
+func massSetup(t *testing.T) (*trenergytest.Server, *trenergy.Client, []int) {
+	t.Helper()
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100*trenergy.OneTRX), trenergytest.WithMassBatchLimit(2))
+	t.Cleanup(fake.Close)
+	client := fake.NewClient()
+	results, err := client.CreateBootstrapOrders(context.Background(), bulkParams(), trenergy.WithWorkers(1))
+	if err != nil {
+		t.Fatalf("CreateBootstrapOrders failed: %v", err)
+	}
+	ids := make([]int, len(results))
+	for i, r := range results {
+		ids[i] = r.Consumer.ID
+	}
+	return fake, client, ids
+}
+
+func TestMassPaymentPeriod(t *testing.T) {
+	fake, client, ids := massSetup(t)
+	ctx := context.Background()
+
+	res, err := client.MassPaymentPeriod(ctx, trenergy.MassPaymentPeriodParams{
+		ConsumerIDs:   ids,
+		PaymentPeriod: trenergy.PaymentPeriod1H,
+		AutoRenewal:   true,
+	}, trenergy.WithBatchSize(2))
+	if err != nil {
+		t.Fatalf("MassPaymentPeriod failed: %v", err)
+	}
+	for i, o := range res.Outcomes {
+		if o.ConsumerID != ids[i] || !o.Verified || o.Consumer == nil {
+			t.Errorf("unexpected outcome %+v", o)
+		}
+	}
+	for _, c := range fake.Consumers() {
+		if c.PaymentPeriod != trenergy.PaymentPeriod1H || !c.AutoRenewal {
+			t.Errorf("consumer %d not updated: %+v", c.ID, c)
+		}
+	}
+
+	// The second chunk names an unknown consumer and is rejected as a whole.
+	res, err = client.MassPaymentPeriod(ctx, trenergy.MassPaymentPeriodParams{
+		ConsumerIDs:   []int{ids[0], ids[1], ids[2], 999},
+		PaymentPeriod: trenergy.PaymentPeriod1D,
+	}, trenergy.WithBatchSize(2))
+	if err == nil || !res.Partial() {
+		t.Fatalf("expected a partial result, got %v", err)
+	}
+	if failed := res.Failed(); len(failed) != 2 || failed[0].ConsumerID != ids[2] || failed[1].ConsumerID != 999 {
+		t.Errorf("unexpected failures %+v", failed)
+	}
+}
+
+func TestMassPaymentPeriodBatchLimit(t *testing.T) {
+	_, client, ids := massSetup(t)
+	_, err := client.MassPaymentPeriod(context.Background(), trenergy.MassPaymentPeriodParams{
+		ConsumerIDs:   ids,
+		PaymentPeriod: trenergy.PaymentPeriod1H,
+	})
+	var apiErr *trenergy.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected the fake to reject an oversized batch, got %v", err)
+	}
+}
+
+func TestMassTrx(t *testing.T) {
+	fake, client, ids := massSetup(t)
+	before := fake.Balance()
+
+	res, err := client.MassTrx(context.Background(), trenergy.MassTrxParams{Consumers: ids, Amount: trenergy.OneTRX},
+		trenergy.WithBatchSize(2))
+	if err != nil {
+		t.Fatalf("MassTrx failed: %v", err)
+	}
+	for _, o := range res.Outcomes {
+		if o.Err != nil || o.Verified {
+			t.Errorf("unexpected outcome %+v", o)
+		}
+	}
+	if spent := before - fake.Balance(); spent != trenergy.TRX(len(ids))*trenergy.OneTRX {
+		t.Errorf("unexpected spend %v", spent)
+	}
+}
+
+func TestMassTrxAmbiguousChunk(t *testing.T) {
+	fake, _, ids := massSetup(t)
+	ctx := context.Background()
+	client := fake.NewClient(trenergy.WithBudget(trenergy.Budget{MaxPerDay: 10 * trenergy.OneTRX}))
+
+	// The first chunk times out on the gateway and may have been paid; the
+	// second is rejected by the server.
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/mass/trx", Status: http.StatusGatewayTimeout})
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/mass/trx", Status: http.StatusUnprocessableEntity})
+	res, err := client.MassTrx(ctx, trenergy.MassTrxParams{Consumers: ids, Amount: trenergy.OneTRX},
+		trenergy.WithBatchSize(2))
+	if err == nil || len(res.Failed()) != len(ids) {
+		t.Fatalf("expected every outcome to fail, got %v", err)
+	}
+	usage, err := client.BudgetUsage(ctx)
+	if err != nil || usage.LastDay != 2*trenergy.OneTRX {
+		t.Errorf("expected the ambiguous chunk to count, got %+v (%v)", usage, err)
+	}
+}
+
+func TestMassReportedOutcomes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/consumers/mass/payment-period":
+			fmt.Fprint(w, `{"status":true,"data":[{"consumer_id":1,"status":true},{"consumer_id":"2","status":false,"message":"Consumer is busy"}]}`)
+		case "/api/consumers/3":
+			fmt.Fprint(w, `{"status":true,"data":{"id":3,"payment_period":15}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not found"}`)
+		}
+	}))
+	defer srv.Close()
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+
+	res, err := client.MassPaymentPeriod(context.Background(), trenergy.MassPaymentPeriodParams{
+		ConsumerIDs:   []int{1, 2, 3},
+		PaymentPeriod: trenergy.PaymentPeriod1H,
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	o := res.Outcomes
+	if o[0].Err != nil || o[0].Verified {
+		t.Errorf("expected consumer 1 to be reported applied, got %+v", o[0])
+	}
+	if !errors.Is(o[1].Err, trenergy.ErrNotApplied) || o[1].Verified {
+		t.Errorf("expected consumer 2 to be reported failed, got %+v", o[1])
+	}
+	if !errors.Is(o[2].Err, trenergy.ErrNotApplied) || !o[2].Verified {
+		t.Errorf("expected consumer 3 to fail verification, got %+v", o[2])
+	}
+}
+
+func TestMassDeactivateAndDelete(t *testing.T) {
+	fake, client, ids := massSetup(t)
+	ctx := context.Background()
+
+	if _, err := client.MassDeactivate(ctx, ids); err != nil {
+		t.Fatalf("MassDeactivate failed: %v", err)
+	}
+	for _, c := range fake.Consumers() {
+		if c.IsActive {
+			t.Errorf("consumer %d still active", c.ID)
+		}
+	}
+
+	res, err := client.MassDelete(ctx, append(ids, 999))
+	if !errors.Is(err, trenergy.ErrNotFound) || len(res.Failed()) != 1 {
+		t.Errorf("expected one ErrNotFound, got %v", err)
+	}
+	if n := len(fake.Consumers()); n != 0 {
+		t.Errorf("expected all consumers deleted, got %d", n)
+	}
+}
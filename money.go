@@ -0,0 +1,155 @@
+package trenergy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TRX is an amount of TRX held as an integer number of SUN, so arithmetic
+// with +, - and integer * is exact. 1 TRX = 1,000,000 SUN.
+type TRX int64
+
+const (
+	// Sun is the smallest TRX unit.
+	Sun TRX = 1
+	// OneTRX is one TRX.
+	OneTRX TRX = 1_000_000
+)
+
+const trxDecimals = 6
+
+// SunToTRX converts an integer amount of SUN.
+func SunToTRX(sun int64) TRX {
+	return TRX(sun)
+}
+
+// TRXFromFloat converts a float amount of TRX, rounding to the nearest SUN.
+// It exists for compatibility with float-based code; prefer ParseTRX.
+func TRXFromFloat(f float64) TRX {
+	return TRX(math.Round(f * float64(OneTRX)))
+}
+
+// decimalAmount matches the amounts accepted by ParseTRX.
+var decimalAmount = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?$`)
+
+// ParseTRX parses a decimal TRX amount such as "1.5" or "-0.000001".
+// Amounts with more than six decimals are rejected, as are fractions such as
+// "1/2" and exponents such as "1e3".
+func ParseTRX(s string) (TRX, error) {
+	s = strings.TrimSpace(s)
+	if !decimalAmount.MatchString(s) {
+		return 0, fmt.Errorf("trenergy: invalid TRX amount %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("trenergy: invalid TRX amount %q", s)
+	}
+	r.Mul(r, big.NewRat(int64(OneTRX), 1))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("trenergy: TRX amount %q is more precise than 1 SUN", s)
+	}
+	if !r.Num().IsInt64() {
+		return 0, fmt.Errorf("trenergy: TRX amount %q is out of range", s)
+	}
+	return TRX(r.Num().Int64()), nil
+}
+
+// parseTRXRounded is like ParseTRX but rounds to the nearest SUN, for
+// amounts coming from the API that carry float artifacts.
+func parseTRXRounded(s string) (TRX, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, fmt.Errorf("trenergy: invalid TRX amount %q", s)
+	}
+	r.Mul(r, big.NewRat(int64(OneTRX), 1))
+	f, _ := r.Float64()
+	if math.Abs(f) > math.MaxInt64 {
+		return 0, fmt.Errorf("trenergy: TRX amount %q is out of range", s)
+	}
+	// Round half away from zero using exact arithmetic.
+	num, den := new(big.Int).Set(r.Num()), r.Denom()
+	half := new(big.Int).Rsh(den, 1)
+	if num.Sign() < 0 {
+		num.Sub(num, half)
+	} else {
+		num.Add(num, half)
+	}
+	num.Quo(num, den)
+	return TRX(num.Int64()), nil
+}
+
+// Sun returns the amount in SUN.
+func (t TRX) Sun() int64 {
+	return int64(t)
+}
+
+// Float64 returns the amount in TRX as a float. The result may be inexact.
+func (t TRX) Float64() float64 {
+	return float64(t) / float64(OneTRX)
+}
+
+// String formats the amount in TRX with no trailing zeros, e.g. "1.5".
+func (t TRX) String() string {
+	sign := ""
+	v := uint64(t)
+	if t < 0 {
+		sign = "-"
+		v = uint64(-t)
+	}
+	whole := v / uint64(OneTRX)
+	frac := v % uint64(OneTRX)
+	if frac == 0 {
+		return sign + strconv.FormatUint(whole, 10)
+	}
+	fs := fmt.Sprintf("%0*d", trxDecimals, frac)
+	return sign + strconv.FormatUint(whole, 10) + "." + strings.TrimRight(fs, "0")
+}
+
+// MarshalText encodes the amount as a decimal string. It is used for form
+// and query encoding.
+func (t TRX) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText parses a decimal string with ParseTRX.
+func (t *TRX) UnmarshalText(text []byte) error {
+	v, err := ParseTRX(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// MarshalJSON encodes the amount as a JSON number.
+func (t TRX) MarshalJSON() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or numeric string, rounding to the
+// nearest SUN. null and "" decode to zero.
+func (t *TRX) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	if s == "null" || s == "" {
+		*t = 0
+		return nil
+	}
+	v, err := parseTRXRounded(s)
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
@@ -0,0 +1,64 @@
+package trenergy_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+)
+
+func TestParseTRX(t *testing.T) {
+	tests := []struct {
+		in   string
+		sun  int64
+		text string
+	}{
+		{"1", 1_000_000, "1"},
+		{"1.5", 1_500_000, "1.5"},
+		{"0.000001", 1, "0.000001"},
+		{"-2.25", -2_250_000, "-2.25"},
+		{"123456.789", 123_456_789_000, "123456.789"},
+		{"+3", 3_000_000, "3"},
+		{" 007.10 ", 7_100_000, "7.1"},
+	}
+	for _, tt := range tests {
+		got, err := trenergy.ParseTRX(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if got.Sun() != tt.sun || got.String() != tt.text {
+			t.Errorf("%s: got %d SUN (%s)", tt.in, got.Sun(), got)
+		}
+	}
+
+	if _, err := trenergy.ParseTRX("0.0000001"); err == nil {
+		t.Error("expected error for sub-SUN precision")
+	}
+	for _, bad := range []string{"abc", "", "1/2", "1e3", "1.5E-2", ".5", "5.", "0x10", "1_000", "+-1", "1.2.3"} {
+		if _, err := trenergy.ParseTRX(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+	if got := trenergy.TRXFromFloat(0.1 + 0.2); got != 300_000 {
+		t.Errorf("expected float conversion to round to 300000 SUN, got %d", got)
+	}
+}
+
+func TestTRXJSON(t *testing.T) {
+	var info trenergy.AccountInfo
+	if err := json.Unmarshal([]byte(`{"balance":12.345678,"credit_limit":"5","energy_balance":0.30000000000000004,"stakes_sum":null}`), &info); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if info.Balance != 12_345_678 || info.CreditLimit != 5*trenergy.OneTRX || info.EnergyBalance != 300_000 || info.StakesSum != 0 {
+		t.Errorf("unexpected amounts %+v", info)
+	}
+
+	b, err := json.Marshal(trenergy.MassTrxParams{Amount: 1_500_000})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(b) != `{"Consumers":null,"Amount":1.5}` {
+		t.Errorf("unexpected encoding %s", b)
+	}
+}
@@ -0,0 +1,255 @@
+package trenergy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+)
+
+// ErrTooManyItems is returned by CollectAll when the iterator yields more
+// items than allowed.
+var ErrTooManyItems = errors.New("trenergy: too many items")
+
+// PageFunc fetches a single page of a list endpoint. Pages start at 1.
+type PageFunc[T any] func(ctx context.Context, page int) (*APIResponse[[]T], error)
+
+// PageOption configures how pages are fetched.
+type PageOption func(*pageConfig)
+
+type pageConfig struct {
+	concurrency int
+}
+
+// WithConcurrency fetches up to n pages in parallel once the first page has
+// reported Meta.LastPage. Items are still yielded in page order, and at most
+// n pages are fetched ahead of the one being yielded. The client's rate
+// limit, if any, applies to every page request.
+func WithConcurrency(n int) PageOption {
+	return func(cfg *pageConfig) {
+		cfg.concurrency = n
+	}
+}
+
+// Paginate walks every page returned by fetch, using Meta.LastPage or
+// Links.Next to decide when to stop. Iteration ends after the first error.
+func Paginate[T any](ctx context.Context, fetch PageFunc[T], opts ...PageOption) iter.Seq2[T, error] {
+	var cfg pageConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.concurrency > 1 {
+		return paginateConcurrent(ctx, fetch, cfg.concurrency)
+	}
+
+	return func(yield func(T, error) bool) {
+		paginateFrom(ctx, fetch, 1, yield)
+	}
+}
+
+// paginateFrom walks the pages in turn, starting at page.
+func paginateFrom[T any](ctx context.Context, fetch PageFunc[T], page int, yield func(T, error) bool) {
+	for ; ; page++ {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		resp, err := fetch(ctx, page)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		for _, item := range resp.Data {
+			if !yield(item, nil) {
+				return
+			}
+		}
+
+		if !hasNextPage(resp, page) {
+			return
+		}
+	}
+}
+
+// pageResult is the outcome of fetching one page.
+type pageResult[T any] struct {
+	items []T
+	err   error
+}
+
+// paginateConcurrent reads the first page sequentially, then fetches the
+// remaining pages with up to n workers and yields them in order. A page is
+// only handed to a worker once fewer than n pages are fetched or waiting to
+// be yielded. It falls back to sequential paging when the first page carries
+// no Meta.LastPage.
+func paginateConcurrent[T any](ctx context.Context, fetch PageFunc[T], n int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		first, err := fetch(ctx, 1)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for _, item := range first.Data {
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if !hasNextPage(first, 1) {
+			return
+		}
+		if first.Meta == nil || first.Meta.LastPage < 2 {
+			// Without a known last page the remaining pages must be walked in turn.
+			paginateFrom(ctx, fetch, 2, yield)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+
+		lastPage := first.Meta.LastPage
+		results := make([]chan pageResult[T], lastPage+1)
+		for page := 2; page <= lastPage; page++ {
+			results[page] = make(chan pageResult[T], 1)
+		}
+
+		pages := make(chan int)
+		// ahead holds a slot for every page handed out and not yet yielded.
+		ahead := make(chan struct{}, n)
+		var wg sync.WaitGroup
+		for range min(n, lastPage-1) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for page := range pages {
+					resp, err := fetch(ctx, page)
+					if err != nil {
+						results[page] <- pageResult[T]{err: err}
+						continue
+					}
+					results[page] <- pageResult[T]{items: resp.Data}
+				}
+			}()
+		}
+		go func() {
+			defer close(pages)
+			for page := 2; page <= lastPage; page++ {
+				select {
+				case ahead <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case pages <- page:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		// Cancel before waiting so that an early return stops the producer
+		// and the in-flight fetches.
+		defer func() {
+			cancel()
+			wg.Wait()
+		}()
+
+		for page := 2; page <= lastPage; page++ {
+			var res pageResult[T]
+			select {
+			case res = <-results[page]:
+			case <-ctx.Done():
+				res.err = ctx.Err()
+			}
+			if res.err != nil {
+				yield(zero, res.err)
+				return
+			}
+			for _, item := range res.items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			<-ahead
+		}
+	}
+}
+
+// hasNextPage reports whether another page follows the given one.
+func hasNextPage[T any](resp *APIResponse[[]T], page int) bool {
+	if len(resp.Data) == 0 {
+		return false
+	}
+	if resp.Meta != nil && resp.Meta.LastPage > 0 {
+		return page < resp.Meta.LastPage
+	}
+	return resp.Links != nil && resp.Links.Next != ""
+}
+
+// CollectAll gathers every item of seq into a slice. If maxItems is positive
+// and seq yields more than maxItems items, the items collected so far are
+// returned together with ErrTooManyItems.
+func CollectAll[T any](seq iter.Seq2[T, error], maxItems int) ([]T, error) {
+	var items []T
+	for item, err := range seq {
+		if err != nil {
+			return items, err
+		}
+		if maxItems > 0 && len(items) >= maxItems {
+			return items, fmt.Errorf("%w: more than %d", ErrTooManyItems, maxItems)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// AllConsumers iterates over every consumer.
+func (c *Client) AllConsumers(ctx context.Context, opts ...PageOption) iter.Seq2[Consumer, error] {
+	return Paginate(ctx, c.ListConsumers, opts...)
+}
+
+// AllConsumersMatching iterates over every consumer matching params.
+// params.Page is ignored.
+func (c *Client) AllConsumersMatching(ctx context.Context, params ListConsumersParams, opts ...PageOption) iter.Seq2[Consumer, error] {
+	return Paginate(ctx, func(ctx context.Context, page int) (*APIResponse[[]Consumer], error) {
+		p := params
+		p.Page = page
+		return c.FilterConsumers(ctx, p)
+	}, opts...)
+}
+
+// AllConsumerPayments iterates over every payment of a consumer.
+func (c *Client) AllConsumerPayments(ctx context.Context, id int, opts ...PageOption) iter.Seq2[ConsumerPayment, error] {
+	return Paginate(ctx, func(ctx context.Context, page int) (*APIResponse[[]ConsumerPayment], error) {
+		return c.GetConsumerPayments(ctx, id, page)
+	}, opts...)
+}
+
+// AllAMLChecks iterates over every AML check.
+func (c *Client) AllAMLChecks(ctx context.Context, opts ...PageOption) iter.Seq2[AMLCheck, error] {
+	return Paginate(ctx, c.ListAMLChecks, opts...)
+}
+
+// AllStakes iterates over every stake.
+func (c *Client) AllStakes(ctx context.Context, opts ...PageOption) iter.Seq2[Stake, error] {
+	return Paginate(ctx, c.ListStakes, opts...)
+}
+
+// AllWithdrawals iterates over every withdrawal.
+func (c *Client) AllWithdrawals(ctx context.Context, opts ...PageOption) iter.Seq2[Withdrawal, error] {
+	return Paginate(ctx, c.ListWithdrawals, opts...)
+}
+
+// AllInternalTransactions iterates over every internal transaction matching
+// params. params.Page is ignored.
+func (c *Client) AllInternalTransactions(ctx context.Context, params InternalTransactionParams, opts ...PageOption) iter.Seq2[InternalTransaction, error] {
+	return Paginate(ctx, func(ctx context.Context, page int) (*APIResponse[[]InternalTransaction], error) {
+		p := params
+		p.Page = page
+		return c.GetInternalTransactions(ctx, p)
+	}, opts...)
+}
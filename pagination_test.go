@@ -0,0 +1,155 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+)
+
+// newPagedServer serves three pages of two consumers each.
+func newPagedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		fmt.Fprintf(w, `{"status":true,"data":[{"id":%d},{"id":%d}],"meta":{"current_page":%d,"last_page":3}}`,
+			page*2-1, page*2, page)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAllConsumers(t *testing.T) {
+	srv := newPagedServer(t)
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+
+	var ids []int
+	for c, err := range client.AllConsumers(context.Background()) {
+		if err != nil {
+			t.Fatalf("AllConsumers failed: %v", err)
+		}
+		ids = append(ids, c.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2 3 4 5 6]" {
+		t.Errorf("unexpected ids %v", ids)
+	}
+}
+
+func TestCollectAllMaxItems(t *testing.T) {
+	srv := newPagedServer(t)
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+
+	items, err := trenergy.CollectAll(client.AllConsumers(context.Background()), 3)
+	if !errors.Is(err, trenergy.ErrTooManyItems) {
+		t.Fatalf("expected ErrTooManyItems, got %v", err)
+	}
+	if len(items) != 3 {
+		t.Errorf("expected 3 items, got %d", len(items))
+	}
+
+	items, err = trenergy.CollectAll(client.AllConsumers(context.Background()), 0)
+	if err != nil || len(items) != 6 {
+		t.Errorf("expected 6 items, got %d (%v)", len(items), err)
+	}
+}
+
+func TestAllConsumersConcurrent(t *testing.T) {
+	srv := newPagedServer(t)
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+
+	items, err := trenergy.CollectAll(client.AllConsumers(context.Background(), trenergy.WithConcurrency(3)), 0)
+	if err != nil {
+		t.Fatalf("AllConsumers failed: %v", err)
+	}
+	var ids []int
+	for _, c := range items {
+		ids = append(ids, c.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2 3 4 5 6]" {
+		t.Errorf("expected ordered ids, got %v", ids)
+	}
+
+	// Stopping early must not leak or block the workers.
+	for c, err := range client.AllConsumers(context.Background(), trenergy.WithConcurrency(3)) {
+		if err != nil {
+			t.Fatalf("AllConsumers failed: %v", err)
+		}
+		if c.ID == 3 {
+			break
+		}
+	}
+}
+
+func TestAllConsumersConcurrentEarlyBreak(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		fmt.Fprintf(w, `{"status":true,"data":[{"id":%d}],"meta":{"current_page":%d,"last_page":200}}`, page, page)
+	}))
+	defer srv.Close()
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+
+	for c, err := range client.AllConsumers(context.Background(), trenergy.WithConcurrency(2)) {
+		if err != nil {
+			t.Fatalf("AllConsumers failed: %v", err)
+		}
+		if c.ID == 2 {
+			break
+		}
+	}
+	if n := hits.Load(); n > 10 {
+		t.Errorf("expected the remaining pages to be abandoned, got %d requests", n)
+	}
+}
+
+func TestPaginateFallbackLastPage(t *testing.T) {
+	var fetched []int
+	fetch := func(_ context.Context, page int) (*trenergy.APIResponse[[]int], error) {
+		fetched = append(fetched, page)
+		resp := &trenergy.APIResponse[[]int]{Data: []int{page}, Links: &trenergy.Links{Next: "next"}}
+		// Only the later pages report the last page.
+		if page > 1 {
+			resp.Meta = &trenergy.Meta{CurrentPage: page, LastPage: 3}
+		}
+		return resp, nil
+	}
+
+	items, err := trenergy.CollectAll(trenergy.Paginate(context.Background(), fetch, trenergy.WithConcurrency(2)), 0)
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if fmt.Sprint(items) != "[1 2 3]" || fmt.Sprint(fetched) != "[1 2 3]" {
+		t.Errorf("expected pages 1 to 3, got items %v from pages %v", items, fetched)
+	}
+}
+
+func TestPaginateConcurrentReadAhead(t *testing.T) {
+	const n = 2
+	var highest atomic.Int32
+	fetch := func(_ context.Context, page int) (*trenergy.APIResponse[[]int], error) {
+		for {
+			h := highest.Load()
+			if int32(page) <= h || highest.CompareAndSwap(h, int32(page)) {
+				break
+			}
+		}
+		return &trenergy.APIResponse[[]int]{Data: []int{page}, Meta: &trenergy.Meta{CurrentPage: page, LastPage: 50}}, nil
+	}
+
+	for page, err := range trenergy.Paginate(context.Background(), fetch, trenergy.WithConcurrency(n)) {
+		if err != nil {
+			t.Fatalf("Paginate failed: %v", err)
+		}
+		// The page being yielded still holds its slot.
+		if h := int(highest.Load()); page > 1 && h > page+n-1 {
+			t.Fatalf("fetched page %d while yielding page %d", h, page)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package trenergy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultPriceCacheTTL is how long the price tables are reused.
+const defaultPriceCacheTTL = time.Minute
+
+// WithPriceCacheTTL sets how long QuoteOrder and the Budget reuse the price
+// tables of ConsumersSummary before fetching them again. Zero disables the
+// cache.
+func WithPriceCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.prices.ttl = ttl
+	}
+}
+
+// Quote is the cost of an order and whether the account can pay for it.
+type Quote struct {
+	Params ConsumerParams
+	// PriceSun is the price in SUN per unit of the resource for the period.
+	PriceSun      float64
+	ResourceCost  TRX
+	ActivationFee TRX
+	TopUpFee      TRX
+	// Total is the sum of the resource cost and the fees.
+	Total TRX
+
+	Balance       TRX
+	EnergyBalance TRX
+	CreditLimit   TRX
+	// Available is what the account can spend on the order: its balance,
+	// energy balance and credit limit together.
+	Available  TRX
+	Affordable bool
+	// Shortfall is how much is missing when the order is not affordable.
+	Shortfall TRX
+}
+
+// Err returns an error wrapping ErrInsufficientFunds if the order is not
+// affordable, or nil.
+func (q *Quote) Err() error {
+	if q.Affordable {
+		return nil
+	}
+	return fmt.Errorf("%w: order costs %s TRX, %s TRX available", ErrInsufficientFunds, q.Total, q.Available)
+}
+
+// QuoteOption configures QuoteOrder.
+type QuoteOption func(*quoteConfig)
+
+type quoteConfig struct {
+	activation bool
+	topUp      bool
+}
+
+// WithAddressActivation adds the address activation fee, for an address that
+// is not yet activated on chain. The API does not report this, so it is up
+// to the caller.
+func WithAddressActivation() QuoteOption {
+	return func(c *quoteConfig) {
+		c.activation = true
+	}
+}
+
+// WithTrxTopUp adds the TRX top-up fee.
+func WithTrxTopUp() QuoteOption {
+	return func(c *quoteConfig) {
+		c.topUp = true
+	}
+}
+
+// QuoteOrder computes the cost of creating a bootstrap order with params and
+// compares it with the account's funds. The price tables are cached (see
+// WithPriceCacheTTL); the account is fetched on every call.
+func (c *Client) QuoteOrder(ctx context.Context, params ConsumerParams, opts ...QuoteOption) (*Quote, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	var cfg quoteConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	prices, err := c.priceTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	price, cost, err := orderCost(prices, params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.GetAccountInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	account := resp.Data
+	if account == nil {
+		account = &AccountInfo{}
+	}
+
+	q := &Quote{
+		Params:        params,
+		PriceSun:      price,
+		ResourceCost:  cost,
+		Balance:       account.Balance,
+		EnergyBalance: account.EnergyBalance,
+		CreditLimit:   account.CreditLimit,
+	}
+	if cfg.activation {
+		q.ActivationFee = prices.AddressActivationFee
+	}
+	if cfg.topUp {
+		q.TopUpFee = prices.TrxTopUpFee
+	}
+	q.Total = q.ResourceCost + q.ActivationFee + q.TopUpFee
+	q.Available = q.Balance + q.EnergyBalance + q.CreditLimit
+	q.Affordable = q.Total <= q.Available
+	if !q.Affordable {
+		q.Shortfall = q.Total - q.Available
+	}
+	return q, nil
+}
+
+// orderCost returns the unit price and the resource cost of an order.
+func orderCost(prices *ConsumersSummary, params ConsumerParams) (float64, TRX, error) {
+	table := prices.PeriodPricesEnergy
+	if params.Resource == ResourceBandwidth {
+		table = prices.PeriodPricesBandwidth
+	}
+	price, ok := table[strconv.Itoa(int(params.PaymentPeriod))]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %w: no %s price for %s", ErrInvalidParams, ErrInvalidPaymentPeriod, params.Resource, params.PaymentPeriod)
+	}
+	return price, TRX(math.Round(float64(params.ResourceAmount) * price)), nil
+}
+
+// priceTables returns the pricing fields of ConsumersSummary, from the cache
+// when it is fresh.
+func (c *Client) priceTables(ctx context.Context) (*ConsumersSummary, error) {
+	if prices, ok := c.prices.get(); ok {
+		return prices, nil
+	}
+	resp, err := c.GetConsumersSummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data == nil {
+		return nil, fmt.Errorf("trenergy: consumers summary has no data")
+	}
+	prices := &ConsumersSummary{
+		PeriodPricesEnergy:    resp.Data.PeriodPricesEnergy,
+		PeriodPricesBandwidth: resp.Data.PeriodPricesBandwidth,
+		TrxTopUpFee:           resp.Data.TrxTopUpFee,
+		AddressActivationFee:  resp.Data.AddressActivationFee,
+		RechargePriceSun:      resp.Data.RechargePriceSun,
+	}
+	c.prices.put(prices)
+	return prices, nil
+}
+
+// priceCache holds the price tables for QuoteOrder and the Budget.
+type priceCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	now       func() time.Time
+	prices    *ConsumersSummary
+	fetchedAt time.Time
+}
+
+func newPriceCache() *priceCache {
+	return &priceCache{ttl: defaultPriceCacheTTL, now: time.Now}
+}
+
+func (pc *priceCache) get() (*ConsumersSummary, bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.prices == nil || pc.now().Sub(pc.fetchedAt) >= pc.ttl {
+		return nil, false
+	}
+	return pc.prices, true
+}
+
+func (pc *priceCache) put(prices *ConsumersSummary) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.prices = prices
+	pc.fetchedAt = pc.now()
+}
@@ -0,0 +1,85 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/trenergytest"
+)
+
+func TestQuoteOrderMatchesCharge(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(trenergy.OneTRX))
+	defer fake.Close()
+	client := fake.NewClient()
+	ctx := context.Background()
+	params := bulkParams()[0]
+
+	q, err := client.QuoteOrder(ctx, params)
+	if err != nil {
+		t.Fatalf("QuoteOrder failed: %v", err)
+	}
+	if q.ResourceCost != 65_000 || q.Total != q.ResourceCost || !q.Affordable || q.Err() != nil {
+		t.Errorf("unexpected quote %+v", q)
+	}
+
+	before := fake.Balance()
+	if _, err := client.CreateBootstrapOrder(ctx, params); err != nil {
+		t.Fatalf("CreateBootstrapOrder failed: %v", err)
+	}
+	if spent := before - fake.Balance(); spent != q.Total {
+		t.Errorf("quoted %v but was charged %v", q.Total, spent)
+	}
+
+	q, err = client.QuoteOrder(ctx, params, trenergy.WithAddressActivation())
+	if err != nil {
+		t.Fatalf("QuoteOrder failed: %v", err)
+	}
+	if q.ActivationFee != 3*trenergy.OneTRX/2 || q.Affordable || q.Shortfall != q.Total-q.Available {
+		t.Errorf("unexpected quote %+v", q)
+	}
+	if !errors.Is(q.Err(), trenergy.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got %v", q.Err())
+	}
+}
+
+func TestQuoteOrderCachesPrices(t *testing.T) {
+	var summaries atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/consumers/summary":
+			summaries.Add(1)
+			fmt.Fprint(w, `{"status":true,"data":{"period_prices_energy":{"60":75.5},"period_prices_bandwidth":{}}}`)
+		case "/api/account":
+			fmt.Fprint(w, `{"status":true,"data":{"balance":0.05,"energy_balance":0.02,"credit_limit":0.01}}`)
+		}
+	}))
+	defer srv.Close()
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	params := bulkParams()[0]
+	params.PaymentPeriod = trenergy.PaymentPeriod1H
+	for range 2 {
+		q, err := client.QuoteOrder(ctx, params)
+		if err != nil {
+			t.Fatalf("QuoteOrder failed: %v", err)
+		}
+		if q.ResourceCost != 75_500 || q.Available != 80_000 || !q.Affordable {
+			t.Errorf("unexpected quote %+v", q)
+		}
+	}
+	if n := summaries.Load(); n != 1 {
+		t.Errorf("expected the prices to be fetched once, got %d", n)
+	}
+
+	params.PaymentPeriod = trenergy.PaymentPeriod1D
+	if _, err := client.QuoteOrder(ctx, params); !errors.Is(err, trenergy.ErrInvalidPaymentPeriod) {
+		t.Errorf("expected ErrInvalidPaymentPeriod, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package trenergy
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WithRateLimit limits the client to rps requests per second with bursts of
+// up to burst requests. The limit is shared by all endpoint methods and is
+// tightened automatically when the server reports its own rate limits, until
+// the server's window resets.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *Client) {
+		if rps <= 0 {
+			c.limiter = nil
+			return
+		}
+		c.limiter = newRateLimiter(rps, burst)
+	}
+}
+
+// send performs a single HTTP round trip, waiting for the rate limiter first.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.limiter == nil {
+		return c.httpClient.Do(req)
+	}
+	if err := c.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err == nil {
+		c.limiter.observe(resp)
+	}
+	return resp, err
+}
+
+// rateLimiter is a token bucket.
+type rateLimiter struct {
+	mu          sync.Mutex
+	rate        float64 // tokens per second
+	configured  float64 // rate before the server lowered it
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	// loweredUntil is when a rate lowered by the server reverts to the
+	// configured rate, or zero.
+	loweredUntil time.Time
+}
+
+// serverWindow is the window of the server's rate limit, assumed when the
+// response does not report when it resets.
+const serverWindow = time.Minute
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:       rps,
+		configured: rps,
+		burst:      float64(burst),
+		tokens:     float64(burst),
+		last:       time.Now(),
+	}
+}
+
+// wait blocks until a token is available or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		d := l.reserve(time.Now())
+		if d == 0 {
+			return nil
+		}
+		if err := sleepContext(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token and returns 0, or returns how long to wait before trying again.
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens += elapsed * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	if !l.loweredUntil.IsZero() && !now.Before(l.loweredUntil) {
+		l.rate = l.configured
+		l.loweredUntil = time.Time{}
+	}
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	d := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if d <= 0 {
+		d = time.Millisecond
+	}
+	return d
+}
+
+// observe adapts the limiter to the X-RateLimit-* and Retry-After headers of resp.
+// The server limit is assumed to be per minute, as is the Laravel default.
+func (l *rateLimiter) observe(resp *http.Response) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	reset, resetErr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil && limit > 0 {
+		if serverRate := float64(limit) / 60; serverRate < l.configured {
+			l.rate = serverRate
+			if l.tokens > l.burst {
+				l.tokens = l.burst
+			}
+			// The server's limit holds until its window resets, after which
+			// the next response reports it again if it still applies.
+			l.loweredUntil = now.Add(serverWindow)
+			if resetErr == nil {
+				l.loweredUntil = time.Unix(reset, 0)
+			}
+		}
+	}
+
+	exhausted := resp.StatusCode == http.StatusTooManyRequests
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil && remaining <= 0 {
+		exhausted = true
+	}
+	if !exhausted {
+		return
+	}
+
+	var until time.Time
+	if d, ok := retryAfter(resp.Header); ok {
+		until = now.Add(d)
+	} else if resetErr == nil {
+		until = time.Unix(reset, 0)
+	} else {
+		until = now.Add(time.Duration(float64(time.Second) / l.rate))
+	}
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+	l.tokens = 0
+}
@@ -0,0 +1,72 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cyvadra/trenergy"
+)
+
+func TestRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":true,"data":{}}`))
+	}))
+	defer srv.Close()
+
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL), trenergy.WithRateLimit(20, 1))
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetAccountInfo(ctx); err != nil {
+			t.Fatalf("GetAccountInfo failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected requests to be spaced by the limiter, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	client.GetAccountInfo(ctx)
+	if _, err := client.GetAccountInfo(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded while waiting for a token, got %v", err)
+	}
+}
+
+func TestRateLimitRestoredAfterReset(t *testing.T) {
+	var lowered bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !lowered {
+			// One request per second until the window resets.
+			lowered = true
+			w.Header().Set("X-RateLimit-Limit", "60")
+			w.Header().Set("X-RateLimit-Remaining", "59")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Second).Unix(), 10))
+		}
+		w.Write([]byte(`{"status":true,"data":{}}`))
+	}))
+	defer srv.Close()
+
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL), trenergy.WithRateLimit(100, 1))
+	ctx := context.Background()
+	if _, err := client.GetAccountInfo(ctx); err != nil {
+		t.Fatalf("GetAccountInfo failed: %v", err)
+	}
+	time.Sleep(time.Second)
+
+	start := time.Now()
+	for range 3 {
+		if _, err := client.GetAccountInfo(ctx); err != nil {
+			t.Fatalf("GetAccountInfo failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the configured rate after the reset, took %v", elapsed)
+	}
+}
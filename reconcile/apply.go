@@ -0,0 +1,172 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cyvadra/trenergy"
+)
+
+// ActionError is an action that failed.
+type ActionError struct {
+	Action Action
+	Err    error
+}
+
+func (e *ActionError) Error() string {
+	return fmt.Sprintf("reconcile: %s %s: %v", e.Action.Kind, e.Action.Address, e.Err)
+}
+
+func (e *ActionError) Unwrap() error {
+	return e.Err
+}
+
+// Result reports the outcome of Apply.
+type Result struct {
+	// Applied lists the actions that succeeded.
+	Applied []Action
+	// Failed lists the actions that failed.
+	Failed []*ActionError
+	// Pending lists the actions that were not attempted, either because of
+	// a dry run or because Apply stopped at a failure.
+	Pending []Action
+}
+
+// Err returns the failures joined into one error, or nil.
+func (r *Result) Err() error {
+	errs := make([]error, len(r.Failed))
+	for i, f := range r.Failed {
+		errs[i] = f
+	}
+	return errors.Join(errs...)
+}
+
+// ApplyOption configures Apply.
+type ApplyOption func(*applyConfig)
+
+type applyConfig struct {
+	dryRun          bool
+	continueOnError bool
+	progress        func(Action, error)
+}
+
+// DryRun makes Apply return without calling the API. Every action is
+// reported as pending.
+func DryRun() ApplyOption {
+	return func(c *applyConfig) {
+		c.dryRun = true
+	}
+}
+
+// ContinueOnError makes Apply carry on with the remaining actions after one
+// fails. By default it stops at the first failure.
+func ContinueOnError() ApplyOption {
+	return func(c *applyConfig) {
+		c.continueOnError = true
+	}
+}
+
+// WithProgress calls fn after each action with its error, if any.
+func WithProgress(fn func(Action, error)) ApplyOption {
+	return func(c *applyConfig) {
+		c.progress = fn
+	}
+}
+
+// Apply executes the plan's actions in order. The returned error is
+// Result.Err, or the context's error if ctx ends first.
+//
+// Every action moves the account closer to the config, so a plan built after
+// a failed Apply contains only the work that is left.
+func Apply(ctx context.Context, c *trenergy.Client, plan *Plan, opts ...ApplyOption) (*Result, error) {
+	cfg := applyConfig{progress: func(Action, error) {}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	res := &Result{}
+	if cfg.dryRun {
+		res.Pending = append(res.Pending, plan.Actions...)
+		return res, nil
+	}
+
+	for i, a := range plan.Actions {
+		if err := ctx.Err(); err != nil {
+			res.Pending = append(res.Pending, plan.Actions[i:]...)
+			return res, err
+		}
+		err := apply(ctx, c, a)
+		cfg.progress(a, err)
+		if err == nil {
+			res.Applied = append(res.Applied, a)
+			continue
+		}
+		res.Failed = append(res.Failed, &ActionError{Action: a, Err: err})
+		if !cfg.continueOnError {
+			res.Pending = append(res.Pending, plan.Actions[i+1:]...)
+			break
+		}
+	}
+	return res, res.Err()
+}
+
+func apply(ctx context.Context, c *trenergy.Client, a Action) error {
+	switch a.Kind {
+	case ActionCreate:
+		return create(ctx, c, a.Spec)
+	case ActionReplace:
+		if _, err := c.DeleteConsumer(ctx, a.ConsumerID); err != nil {
+			return err
+		}
+		return create(ctx, c, a.Spec)
+	case ActionDelete:
+		_, err := c.DeleteConsumer(ctx, a.ConsumerID)
+		return err
+	case ActionActivate:
+		_, err := c.ActivateConsumer(ctx, a.ConsumerID)
+		return err
+	case ActionDeactivate:
+		_, err := c.DeactivateConsumer(ctx, a.ConsumerID)
+		return err
+	case ActionUpdate:
+		_, err := c.UpdateConsumer(ctx, a.ConsumerID, a.update())
+		return err
+	}
+	return fmt.Errorf("unknown action %q", a.Kind)
+}
+
+// update returns the parameters that apply the action's changes.
+func (a Action) update() trenergy.UpdateConsumerParams {
+	var p trenergy.UpdateConsumerParams
+	if a.changed("name") {
+		p.Name = &a.Spec.Name
+	}
+	if a.changed("resource_amount") {
+		p.ResourceAmount = &a.Spec.ResourceAmount
+	}
+	if a.changed("payment_period") {
+		p.PaymentPeriod = &a.Spec.PaymentPeriod
+	}
+	if a.changed("auto_renewal") {
+		p.AutoRenewal = &a.Spec.AutoRenewal
+	}
+	if a.changed("webhook_url") {
+		p.WebhookURL = &a.Spec.WebhookURL
+	}
+	return p
+}
+
+func create(ctx context.Context, c *trenergy.Client, spec *Spec) error {
+	resp, err := c.CreateBootstrapOrder(ctx, spec.Params())
+	if err != nil {
+		return err
+	}
+	if resp.Data == nil {
+		return errors.New("bootstrap order returned no consumer")
+	}
+	if !spec.IsActive() {
+		_, err = c.DeactivateConsumer(ctx, resp.Data.ID)
+	}
+	return err
+}
@@ -0,0 +1,375 @@
+// Package reconcile keeps the consumers of a Tr.Energy account in line with a
+// declarative description.
+//
+// The desired state is a Config, usually loaded from a JSON or YAML file:
+//
+//	{
+//	  "prune": true,
+//	  "consumers": [
+//	    {"address": "TG5F...", "name": "hot wallet", "resource": "energy",
+//	     "resource_amount": 65000, "payment_period": 60, "auto_renewal": true}
+//	  ]
+//	}
+//
+// or, in YAML:
+//
+//	prune: true
+//	consumers:
+//	  - address: TG5F...
+//	    name: hot wallet
+//	    resource: energy
+//	    resource_amount: 65000
+//	    payment_period: 60
+//	    auto_renewal: true
+//
+// BuildPlan compares it with the consumers returned by the API and returns the
+// actions needed to converge, which can be printed as a diff and executed with
+// Apply. Applying is idempotent: after a partial failure, building a new plan
+// and applying it again resumes from where the previous run stopped.
+//
+// YAML files are read without external dependencies, so only the block-style
+// subset shown above is supported: mappings, sequences, quoted and plain
+// scalars and comments. Anchors, tags, block scalars and flow collections
+// other than [] and {} are rejected.
+package reconcile
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cyvadra/trenergy"
+)
+
+// Config is the desired state of the account's consumers.
+type Config struct {
+	// Consumers lists the desired consumers, one per address.
+	Consumers []Spec `json:"consumers"`
+	// Prune deletes consumers whose address is not listed, as well as
+	// duplicate consumers for a listed address.
+	Prune bool `json:"prune,omitempty"`
+}
+
+// Spec is the desired state of one consumer.
+type Spec struct {
+	Address string `json:"address"`
+	// Name is left unchanged when empty.
+	Name string `json:"name,omitempty"`
+	// Resource defaults to energy.
+	Resource       trenergy.Resource      `json:"resource"`
+	ResourceAmount int64                  `json:"resource_amount"`
+	PaymentPeriod  trenergy.PaymentPeriod `json:"payment_period"`
+	AutoRenewal    bool                   `json:"auto_renewal"`
+	// WebhookURL is left unchanged when empty.
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// Active defaults to true.
+	Active *bool `json:"active,omitempty"`
+}
+
+// UnmarshalJSON applies the defaults for omitted fields and rejects unknown
+// ones, so that typos do not silently fall back to defaults.
+func (s *Spec) UnmarshalJSON(data []byte) error {
+	type plain Spec
+	p := plain{Resource: trenergy.ResourceEnergy}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return err
+	}
+	*s = Spec(p)
+	return nil
+}
+
+// IsActive reports whether the consumer should be active.
+func (s Spec) IsActive() bool {
+	return s.Active == nil || *s.Active
+}
+
+// Params returns the parameters used to create the consumer.
+func (s Spec) Params() trenergy.ConsumerParams {
+	return trenergy.ConsumerParams{
+		Address:        s.Address,
+		PaymentPeriod:  s.PaymentPeriod,
+		AutoRenewal:    s.AutoRenewal,
+		ResourceAmount: s.ResourceAmount,
+		Name:           s.Name,
+		Resource:       s.Resource,
+		WebhookURL:     s.WebhookURL,
+	}
+}
+
+// Load decodes a JSON Config from r. Unknown fields are rejected.
+func Load(r io.Reader) (*Config, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("reconcile: decoding config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// LoadYAML decodes a YAML Config from r. It applies the same defaults and
+// checks as Load; see the package documentation for the supported subset.
+func LoadYAML(r io.Reader) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err = yamlToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: decoding config: %w", err)
+	}
+	return Load(bytes.NewReader(data))
+}
+
+// LoadFile reads a Config from path, as YAML if its extension is .yaml or
+// .yml and as JSON otherwise.
+func LoadFile(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return LoadYAML(f)
+	}
+	return Load(f)
+}
+
+// ActionKind is the operation an Action performs.
+type ActionKind string
+
+const (
+	ActionCreate     ActionKind = "create"
+	ActionUpdate     ActionKind = "update"
+	ActionActivate   ActionKind = "activate"
+	ActionDeactivate ActionKind = "deactivate"
+	// ActionReplace deletes the consumer and creates it again, because its
+	// resource cannot be changed in place.
+	ActionReplace ActionKind = "replace"
+	ActionDelete  ActionKind = "delete"
+)
+
+// Change is a field whose value differs between the account and the config.
+type Change struct {
+	Field string
+	From  string
+	To    string
+}
+
+// Action is one step of a Plan.
+type Action struct {
+	Kind    ActionKind
+	Address string
+	// ConsumerID is the existing consumer, or zero for ActionCreate.
+	ConsumerID int
+	// Spec is the desired state, or nil for ActionDelete.
+	Spec    *Spec
+	Changes []Change
+}
+
+// changed reports whether the action changes field.
+func (a Action) changed(field string) bool {
+	return slices.ContainsFunc(a.Changes, func(c Change) bool { return c.Field == field })
+}
+
+// String formats the action as a diff line followed by its changes.
+func (a Action) String() string {
+	var b strings.Builder
+	switch a.Kind {
+	case ActionCreate:
+		fmt.Fprintf(&b, "+ create %s", a.Address)
+	case ActionDelete:
+		fmt.Fprintf(&b, "- delete %s (#%d)", a.Address, a.ConsumerID)
+	case ActionReplace:
+		fmt.Fprintf(&b, "-/+ replace %s (#%d)", a.Address, a.ConsumerID)
+	default:
+		fmt.Fprintf(&b, "~ %s %s (#%d)", a.Kind, a.Address, a.ConsumerID)
+	}
+	for _, c := range a.Changes {
+		if c.From == "" {
+			fmt.Fprintf(&b, "\n    %s: %s", c.Field, c.To)
+		} else {
+			fmt.Fprintf(&b, "\n    %s: %s -> %s", c.Field, c.From, c.To)
+		}
+	}
+	return b.String()
+}
+
+// Plan is the list of actions that brings the account to the desired state.
+type Plan struct {
+	Actions []Action
+}
+
+// Empty reports whether the account already matches the config.
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// String formats the plan as a diff, one action per line.
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "no changes"
+	}
+	lines := make([]string, len(p.Actions))
+	for i, a := range p.Actions {
+		lines[i] = a.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// BuildPlan lists the account's consumers and compares them with cfg.
+func BuildPlan(ctx context.Context, c *trenergy.Client, cfg *Config) (*Plan, error) {
+	current, err := trenergy.CollectAll(c.AllConsumers(ctx), 0)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: listing consumers: %w", err)
+	}
+	return Diff(current, cfg)
+}
+
+// Diff compares the current consumers with cfg. It fails if cfg lists an
+// address twice or contains an invalid consumer.
+func Diff(current []trenergy.Consumer, cfg *Config) (*Plan, error) {
+	byAddress := make(map[string][]trenergy.Consumer)
+	for _, c := range current {
+		key := canonical(c.Address)
+		byAddress[key] = append(byAddress[key], c)
+	}
+	for _, cs := range byAddress {
+		slices.SortFunc(cs, func(a, b trenergy.Consumer) int { return cmp.Compare(a.ID, b.ID) })
+	}
+
+	plan := &Plan{}
+	wanted := make(map[string]bool)
+	for i := range cfg.Consumers {
+		spec := &cfg.Consumers[i]
+		if err := spec.Params().Validate(); err != nil {
+			return nil, fmt.Errorf("reconcile: consumer %d (%s): %w", i, spec.Address, err)
+		}
+		key := canonical(spec.Address)
+		if wanted[key] {
+			return nil, fmt.Errorf("reconcile: address %s is listed more than once", spec.Address)
+		}
+		wanted[key] = true
+
+		existing := byAddress[key]
+		if len(existing) == 0 {
+			plan.Actions = append(plan.Actions, Action{
+				Kind:    ActionCreate,
+				Address: spec.Address,
+				Spec:    spec,
+				Changes: creation(spec),
+			})
+			continue
+		}
+		plan.Actions = append(plan.Actions, compare(&existing[0], spec)...)
+		if cfg.Prune {
+			for _, dup := range existing[1:] {
+				plan.Actions = append(plan.Actions, deletion(dup))
+			}
+		}
+	}
+
+	if cfg.Prune {
+		var extra []trenergy.Consumer
+		for key, cs := range byAddress {
+			if !wanted[key] {
+				extra = append(extra, cs...)
+			}
+		}
+		slices.SortFunc(extra, func(a, b trenergy.Consumer) int { return cmp.Compare(a.ID, b.ID) })
+		for _, c := range extra {
+			plan.Actions = append(plan.Actions, deletion(c))
+		}
+	}
+	return plan, nil
+}
+
+// compare returns the actions that turn c into spec.
+func compare(c *trenergy.Consumer, spec *Spec) []Action {
+	action := func(kind ActionKind, changes []Change) Action {
+		return Action{Kind: kind, Address: spec.Address, ConsumerID: c.ID, Spec: spec, Changes: changes}
+	}
+
+	// The resource cannot be changed on an existing consumer.
+	if c.Resource != spec.Resource {
+		return []Action{action(ActionReplace, []Change{{"resource", c.Resource.String(), spec.Resource.String()}})}
+	}
+
+	amount := c.DesiredResourceAmount
+	if !amount.Valid {
+		amount = c.ResourceAmount
+	}
+
+	var actions []Action
+	var changes []Change
+	if spec.Name != "" && c.Name != spec.Name {
+		changes = append(changes, Change{"name", strconv.Quote(c.Name), strconv.Quote(spec.Name)})
+	}
+	if amount.Value != spec.ResourceAmount {
+		changes = append(changes, Change{"resource_amount", strconv.FormatInt(amount.Value, 10), strconv.FormatInt(spec.ResourceAmount, 10)})
+	}
+	if c.PaymentPeriod != spec.PaymentPeriod {
+		changes = append(changes, Change{"payment_period", c.PaymentPeriod.String(), spec.PaymentPeriod.String()})
+	}
+	if c.AutoRenewal != spec.AutoRenewal {
+		changes = append(changes, Change{"auto_renewal", strconv.FormatBool(c.AutoRenewal), strconv.FormatBool(spec.AutoRenewal)})
+	}
+	if spec.WebhookURL != "" && c.WebhookURL != spec.WebhookURL {
+		changes = append(changes, Change{"webhook_url", strconv.Quote(c.WebhookURL), strconv.Quote(spec.WebhookURL)})
+	}
+	if len(changes) > 0 {
+		actions = append(actions, action(ActionUpdate, changes))
+	}
+
+	if c.IsActive != spec.IsActive() {
+		kind := ActionDeactivate
+		if spec.IsActive() {
+			kind = ActionActivate
+		}
+		actions = append(actions, action(kind, []Change{{"active", strconv.FormatBool(c.IsActive), strconv.FormatBool(spec.IsActive())}}))
+	}
+	return actions
+}
+
+// creation describes the consumer that spec creates.
+func creation(spec *Spec) []Change {
+	changes := []Change{
+		{Field: "resource", To: spec.Resource.String()},
+		{Field: "resource_amount", To: strconv.FormatInt(spec.ResourceAmount, 10)},
+		{Field: "payment_period", To: spec.PaymentPeriod.String()},
+		{Field: "auto_renewal", To: strconv.FormatBool(spec.AutoRenewal)},
+	}
+	if spec.Name != "" {
+		changes = append(changes, Change{Field: "name", To: strconv.Quote(spec.Name)})
+	}
+	if spec.WebhookURL != "" {
+		changes = append(changes, Change{Field: "webhook_url", To: strconv.Quote(spec.WebhookURL)})
+	}
+	if !spec.IsActive() {
+		changes = append(changes, Change{Field: "active", To: "false"})
+	}
+	return changes
+}
+
+func deletion(c trenergy.Consumer) Action {
+	return Action{Kind: ActionDelete, Address: c.Address, ConsumerID: c.ID}
+}
+
+// canonical normalises an address so that hex and base58 forms compare equal.
+func canonical(address string) string {
+	if a, err := trenergy.ParseAddress(address); err == nil {
+		return a.String()
+	}
+	return address
+}
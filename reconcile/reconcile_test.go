@@ -0,0 +1,235 @@
+package reconcile_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/reconcile"
+	"github.com/cyvadra/trenergy/trenergytest"
+)
+
+const addrA = "TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF"
+
+const config = `{
+	"prune": true,
+	"consumers": [
+		{"address": "TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF", "name": "hot", "resource_amount": 65000, "payment_period": "1h", "auto_renewal": true, "active": false},
+		{"address": "TKzxdSv2FZKQrEqkKVgp5DcwEXBEKMg2Ax", "resource_amount": 32000, "payment_period": 15}
+	]
+}`
+
+func setup(t *testing.T) (*trenergytest.Server, *trenergy.Client) {
+	t.Helper()
+	fake := trenergytest.NewServer(trenergytest.WithBalance(1000 * trenergy.OneTRX))
+	t.Cleanup(fake.Close)
+	client := fake.NewClient(trenergy.WithRetryPolicy(trenergy.RetryPolicy{MaxAttempts: 1}))
+
+	// addrA exists with other settings and a duplicate; addrB is missing.
+	for _, name := range []string{"old", "dup"} {
+		_, err := client.CreateBootstrapOrder(context.Background(), trenergy.ConsumerParams{
+			Address:        addrA,
+			Name:           name,
+			PaymentPeriod:  trenergy.PaymentPeriod15M,
+			ResourceAmount: 65000,
+			Resource:       trenergy.ResourceEnergy,
+		})
+		if err != nil {
+			t.Fatalf("CreateBootstrapOrder failed: %v", err)
+		}
+	}
+	return fake, client
+}
+
+func TestPlanAndApply(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.Background()
+
+	cfg, err := reconcile.Load(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Consumers[1].Resource != trenergy.ResourceEnergy {
+		t.Errorf("expected resource to default to energy, got %v", cfg.Consumers[1].Resource)
+	}
+
+	plan, err := reconcile.BuildPlan(ctx, client, cfg)
+	if err != nil {
+		t.Fatalf("BuildPlan failed: %v", err)
+	}
+	want := `~ update TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF (#1)
+    name: "old" -> "hot"
+    payment_period: 15m -> 1h
+    auto_renewal: false -> true
+~ deactivate TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF (#1)
+    active: true -> false
+- delete TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF (#3)
++ create TKzxdSv2FZKQrEqkKVgp5DcwEXBEKMg2Ax
+    resource: energy
+    resource_amount: 32000
+    payment_period: 15m
+    auto_renewal: false`
+	if got := plan.String(); got != want {
+		t.Errorf("unexpected plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	res, err := reconcile.Apply(ctx, client, plan, reconcile.DryRun())
+	if err != nil || len(res.Pending) != 4 || len(res.Applied) != 0 {
+		t.Fatalf("unexpected dry run result %+v, %v", res, err)
+	}
+	if n := len(fake.Consumers()); n != 2 {
+		t.Fatalf("dry run changed the account: %d consumers", n)
+	}
+
+	res, err = reconcile.Apply(ctx, client, plan)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(res.Applied) != 4 {
+		t.Errorf("expected 4 applied actions, got %d", len(res.Applied))
+	}
+
+	consumers := fake.Consumers()
+	if len(consumers) != 2 {
+		t.Fatalf("expected 2 consumers, got %d", len(consumers))
+	}
+	a := consumers[0]
+	if a.Name != "hot" || a.PaymentPeriod != trenergy.PaymentPeriod1H || !a.AutoRenewal || a.IsActive {
+		t.Errorf("consumer A not reconciled: %+v", a)
+	}
+
+	plan, err = reconcile.BuildPlan(ctx, client, cfg)
+	if err != nil {
+		t.Fatalf("BuildPlan failed: %v", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("expected empty plan after apply, got:\n%s", plan)
+	}
+}
+
+func TestApplyResumesAfterFailure(t *testing.T) {
+	fake, client := setup(t)
+	ctx := context.Background()
+	cfg, err := reconcile.Load(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	plan, err := reconcile.BuildPlan(ctx, client, cfg)
+	if err != nil {
+		t.Fatalf("BuildPlan failed: %v", err)
+	}
+	fake.InjectFailure(trenergytest.Failure{Method: http.MethodDelete, Status: http.StatusServiceUnavailable})
+
+	res, err := reconcile.Apply(ctx, client, plan)
+	var actionErr *reconcile.ActionError
+	if !errors.As(err, &actionErr) || actionErr.Action.Kind != reconcile.ActionDelete {
+		t.Fatalf("expected delete to fail, got %v", err)
+	}
+	if len(res.Applied) != 2 || len(res.Failed) != 1 || len(res.Pending) != 1 {
+		t.Fatalf("unexpected result: applied=%d failed=%d pending=%d", len(res.Applied), len(res.Failed), len(res.Pending))
+	}
+
+	plan, err = reconcile.BuildPlan(ctx, client, cfg)
+	if err != nil {
+		t.Fatalf("BuildPlan failed: %v", err)
+	}
+	if len(plan.Actions) != 2 {
+		t.Fatalf("expected delete and create to remain, got:\n%s", plan)
+	}
+	if _, err := reconcile.Apply(ctx, client, plan); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if n := len(fake.Consumers()); n != 2 {
+		t.Errorf("expected 2 consumers, got %d", n)
+	}
+}
+
+func TestReplaceAndValidation(t *testing.T) {
+	current := []trenergy.Consumer{{
+		ID:             5,
+		Address:        addrA,
+		Resource:       trenergy.ResourceEnergy,
+		ResourceAmount: trenergy.NewFlexInt(65000),
+		PaymentPeriod:  trenergy.PaymentPeriod1H,
+		IsActive:       true,
+	}}
+	plan, err := reconcile.Diff(current, &reconcile.Config{Consumers: []reconcile.Spec{{
+		Address:        addrA,
+		Resource:       trenergy.ResourceBandwidth,
+		ResourceAmount: 65000,
+		PaymentPeriod:  trenergy.PaymentPeriod1H,
+	}}})
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Kind != reconcile.ActionReplace {
+		t.Errorf("expected a replace, got:\n%s", plan)
+	}
+
+	_, err = reconcile.Diff(nil, &reconcile.Config{Consumers: []reconcile.Spec{{Address: "nope", ResourceAmount: 1, PaymentPeriod: 15}}})
+	if !errors.Is(err, trenergy.ErrInvalidAddress) {
+		t.Errorf("expected ErrInvalidAddress, got %v", err)
+	}
+
+	spec := reconcile.Spec{Address: addrA, Resource: trenergy.ResourceEnergy, ResourceAmount: 1, PaymentPeriod: 15}
+	_, err = reconcile.Diff(nil, &reconcile.Config{Consumers: []reconcile.Spec{spec, spec}})
+	if err == nil {
+		t.Error("expected an error for a duplicate address")
+	}
+
+	if _, err := reconcile.Load(strings.NewReader(`{"consumers": [{"adress": "x"}]}`)); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
+
+const yamlConfig = `---
+# Same as config.
+prune: true
+consumers:
+- address: TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF
+  name: "hot"   # quoted
+  resource_amount: 65000
+  payment_period: 1h
+  auto_renewal: true
+  active: false
+- address: 'TKzxdSv2FZKQrEqkKVgp5DcwEXBEKMg2Ax'
+  resource_amount: 32000
+  payment_period: 15
+`
+
+func TestLoadYAML(t *testing.T) {
+	want, err := reconcile.Load(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "consumers.yaml")
+	if err := os.WriteFile(path, []byte(yamlConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := reconcile.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("YAML and JSON configs differ:\n%+v\n%+v", got, want)
+	}
+
+	for _, bad := range []string{
+		"consumers:\n  - address: a\n    color: red\n",
+		"consumers:\n  - &a {address: a}\n",
+		"prune: true\n  consumers: []\n",
+		"prune: true\nprune: false\n",
+		"consumers:\n  - address: |\n      a\n",
+	} {
+		if _, err := reconcile.LoadYAML(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error loading %q", bad)
+		}
+	}
+}
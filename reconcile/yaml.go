@@ -0,0 +1,343 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// yamlToJSON converts the block-style YAML subset used by config files to
+// JSON, so that it goes through the same decoding and validation as JSON.
+//
+// Supported are block mappings and sequences, plain, single- and
+// double-quoted scalars on one line, the empty flow collections [] and {},
+// comments and a leading "---". Scalars follow the YAML 1.2 core schema: true, false,
+// null, ~ and numbers are typed, everything else is a string. Anchors,
+// aliases, tags, block scalars (| and >), non-empty flow collections and
+// multiple documents are rejected.
+func yamlToJSON(data []byte) ([]byte, error) {
+	p := &yamlParser{}
+	if err := p.split(string(data)); err != nil {
+		return nil, err
+	}
+	if len(p.lines) == 0 {
+		return []byte("null"), nil
+	}
+	v, err := p.node(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return json.Marshal(v)
+}
+
+// yamlLine is a non-empty line with its comment removed.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	num := 0
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	} else if len(p.lines) > 0 {
+		num = p.lines[len(p.lines)-1].num
+	}
+	return fmt.Errorf("yaml: line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+// split breaks data into lines, dropping blank lines, comments and the
+// document start marker.
+func (p *yamlParser) split(data string) error {
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		text := stripComment(raw)
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return fmt.Errorf("yaml: line %d: tabs are not allowed in indentation", i+1)
+		}
+		if trimmed == "---" || strings.HasPrefix(trimmed, "--- ") {
+			if len(p.lines) > 0 {
+				return fmt.Errorf("yaml: line %d: multiple documents are not supported", i+1)
+			}
+			continue
+		}
+		if trimmed == "..." {
+			break
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: strings.TrimRight(trimmed, " \t")})
+	}
+	return nil
+}
+
+// stripComment removes a comment: a # at the start of the line or after
+// whitespace, outside quotes.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote == '"' && c == '\\', quote == '\'' && c == '\'' && i+1 < len(line) && line[i+1] == '\'':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || line[i-1] == ' '):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// node parses the value starting at the current line, which is indented by
+// indent.
+func (p *yamlParser) node(indent int) (any, error) {
+	l := p.lines[p.pos]
+	if isSeqItem(l.text) {
+		return p.sequence(indent)
+	}
+	if _, _, ok := splitKey(l.text); ok {
+		return p.mapping(indent)
+	}
+	p.pos++
+	return scalar(l.text)
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	items := []any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent || !isSeqItem(l.text) {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		rest := strings.TrimLeft(l.text[1:], " ")
+		var item any
+		var err error
+		if rest == "" {
+			p.pos++
+			item, err = p.nested(indent)
+		} else {
+			// The item continues on this line, as in "- key: value"; parse
+			// it as if it started on its own line at the column of rest.
+			col := l.indent + len(l.text) - len(rest)
+			p.lines[p.pos] = yamlLine{num: l.num, indent: col, text: rest}
+			item, err = p.node(col)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	m := make(map[string]any)
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent || isSeqItem(l.text) {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		key, value, ok := splitKey(l.text)
+		if !ok {
+			return nil, p.errorf("expected a key")
+		}
+		key, err := unquoteKey(key)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		var v any
+		if value == "" {
+			p.pos++
+			v, err = p.nested(indent)
+			// A sequence may start at the indentation of its key.
+			if err == nil && v == nil && p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSeqItem(p.lines[p.pos].text) {
+				v, err = p.sequence(indent)
+			}
+		} else {
+			v, err = scalar(value)
+			p.pos++
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// nested parses the block value of a key or sequence item at indent, or
+// returns nil if the next line is not indented further.
+func (p *yamlParser) nested(indent int) (any, error) {
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+		return nil, nil
+	}
+	return p.node(p.lines[p.pos].indent)
+}
+
+// splitKey splits "key: value" at the first colon followed by a space or
+// the end of the line, outside quotes.
+func splitKey(text string) (key, value string, ok bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+func unquoteKey(key string) (string, error) {
+	v, err := scalar(key)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return key, nil
+}
+
+// yamlEscapes maps the single-character escapes of double-quoted scalars to
+// their values.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+	'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"",
+	'/': "/", '\\': "\\", 'N': "\u0085", '_': "\u00a0", 'L': "\u2028",
+	'P': "\u2029",
+}
+
+// unquoteDouble decodes a double-quoted scalar on a single line, with the
+// escapes of YAML 1.2. \x, \u and \U name a code point.
+func unquoteDouble(s string) (string, bool) {
+	if len(s) < 2 || s[len(s)-1] != '"' {
+		return "", false
+	}
+	s = s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			return "", false
+		case c != '\\':
+			b.WriteByte(c)
+			continue
+		case i+1 == len(s):
+			return "", false
+		}
+		i++
+		if v, ok := yamlEscapes[s[i]]; ok {
+			b.WriteString(v)
+			continue
+		}
+		var digits int
+		switch s[i] {
+		case 'x':
+			digits = 2
+		case 'u':
+			digits = 4
+		case 'U':
+			digits = 8
+		}
+		if digits == 0 || i+digits >= len(s) {
+			return "", false
+		}
+		r, err := strconv.ParseUint(s[i+1:i+1+digits], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return "", false
+		}
+		b.WriteRune(rune(r))
+		i += digits
+	}
+	return b.String(), true
+}
+
+var (
+	yamlInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// scalar decodes a scalar value.
+func scalar(s string) (any, error) {
+	switch {
+	case s == "":
+		return nil, nil
+	case s[0] == '"':
+		v, ok := unquoteDouble(s)
+		if !ok {
+			return nil, fmt.Errorf("yaml: invalid quoted string %s", s)
+		}
+		return v, nil
+	case s[0] == '\'':
+		body, ok := strings.CutPrefix(s, "'")
+		if body, ok = strings.CutSuffix(body, "'"); !ok || strings.Contains(strings.ReplaceAll(body, "''", ""), "'") {
+			return nil, fmt.Errorf("yaml: invalid quoted string %s", s)
+		}
+		return strings.ReplaceAll(body, "''", "'"), nil
+	case s == "[]":
+		return []any{}, nil
+	case s == "{}":
+		return map[string]any{}, nil
+	case strings.ContainsRune("[{&*!|>%@`", rune(s[0])):
+		return nil, fmt.Errorf("yaml: unsupported value %s", s)
+	}
+
+	switch s {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	if yamlInt.MatchString(s) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(n, 10)), nil
+		}
+	}
+	if yamlFloat.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,73 @@
+package reconcile
+
+import "testing"
+
+func TestYAMLToJSON(t *testing.T) {
+	for _, tt := range []struct {
+		name, in, want string
+	}{
+		{"empty", "# nothing\n", `null`},
+		{"mapping", "a: 1\nb: x\n", `{"a":1,"b":"x"}`},
+		{"nested mapping", "a:\n  b:\n    c: true\n", `{"a":{"b":{"c":true}}}`},
+		{"sequence at key indent", "a:\n- 1\n- 2\n", `{"a":[1,2]}`},
+		{"sequence of mappings", "- a: 1\n  b: 2\n- a: 3\n", `[{"a":1,"b":2},{"a":3}]`},
+		{"empty collections", "a: []\nb: {}\n", `{"a":[],"b":{}}`},
+		{"document marker", "---\na: 1\n...\nignored\n", `{"a":1}`},
+		{"comments", "a: x # c\nb: x#y\n# c\n", `{"a":"x","b":"x#y"}`},
+		{"core schema", "a: ~\nb: Null\nc: FALSE\nd: -7\ne: 1.5e3\nf: .5\ng: 0x1f\nh: yes\n",
+			`{"a":null,"b":null,"c":false,"d":-7,"e":1500,"f":0.5,"g":"0x1f","h":"yes"}`},
+		{"empty value", "a:\nb: 1\n", `{"a":null,"b":1}`},
+		{"quoted key", "\"a: b\": 1\n'c': 2\n", `{"a: b":1,"c":2}`},
+		{"quoted number", "a: \"1\"\nb: '2'\n", `{"a":"1","b":"2"}`},
+		{"single quotes", "a: 'it''s # not a comment'\n", `{"a":"it's # not a comment"}`},
+		{"double quote escapes", `a: "\"\\\/\t\n\0\e\ "` + "\n", `{"a":"\"\\/\t\n\u0000\u001b "}`},
+		{"code points", `a: "\x41\xe9\u00e9\U0001F600"` + "\n", `{"a":"Aéé😀"}`},
+		{"unicode escapes", `a: "\N\_\L\P"` + "\n", "{\"a\":\"\u0085\u00a0\\u2028\\u2029\"}"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := yamlToJSON([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("yamlToJSON failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYAMLToJSONRejects(t *testing.T) {
+	for _, tt := range []struct {
+		name, in string
+	}{
+		{"tab indentation", "a:\n\tb: 1\n"},
+		{"multiple documents", "a: 1\n---\nb: 2\n"},
+		{"unexpected indentation", "a: 1\n  b: 2\n"},
+		{"duplicate key", "a: 1\na: 2\n"},
+		{"anchor", "a: &x 1\n"},
+		{"alias", "a: *x\n"},
+		{"tag", "a: !!str 1\n"},
+		{"block scalar", "a: |\n  x\n"},
+		{"folded scalar", "a: >\n  x\n"},
+		{"flow sequence", "a: [1, 2]\n"},
+		{"flow mapping", "a: {b: 1}\n"},
+		{"multi-line double quotes", "a: \"x\n  y\"\n"},
+		{"multi-line single quotes", "a: 'x\n  y'\n"},
+		{"unterminated double quotes", "a: \"x\n"},
+		{"stray double quote", `a: "x"y"` + "\n"},
+		{"stray single quote", "a: 'x'y'\n"},
+		{"unknown escape", `a: "\q"` + "\n"},
+		{"single quote escape", `a: "\'"` + "\n"},
+		{"octal escape", `a: "\101"` + "\n"},
+		{"short hex escape", `a: "\x4"` + "\n"},
+		{"invalid code point", `a: "\ud800"` + "\n"},
+		{"trailing backslash", `a: "x\"` + "\n"},
+		{"mixed sequence and mapping", "- 1\na: 2\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := yamlToJSON([]byte(tt.in)); err == nil {
+				t.Errorf("expected an error, got %s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+// Package renewal renews consumer orders before they expire.
+//
+// A Scheduler periodically lists the consumers that do not renew
+// automatically and, once an order is within the lead time of its
+// ValidUntil, either places a new bootstrap order for the same address and
+// deactivates the old consumer, or turns on auto-renewal:
+//
+//	s := renewal.New(client,
+//		renewal.WithLeadTime(10*time.Minute),
+//		renewal.WithStore(renewal.NewFileStore("renewals.json")),
+//		renewal.WithEventHandler(func(e renewal.Event) { log.Println(e) }))
+//	err := s.Run(ctx)
+//
+// Idle detection is off by default: every due order is renewed, whether or
+// not its consumer is used. Pass WithIdlePolicy to skip idle consumers.
+package renewal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyvadra/trenergy"
+)
+
+// Method is how an order is renewed.
+type Method int
+
+const (
+	// MethodBootstrap places a new bootstrap order with the same parameters
+	// and then deactivates the old consumer, so that each address keeps a
+	// single active consumer.
+	MethodBootstrap Method = iota
+	// MethodAutoRenewal turns on auto-renewal for the consumer, after which
+	// the scheduler no longer tracks it.
+	MethodAutoRenewal
+)
+
+// EventKind identifies an Event.
+type EventKind string
+
+const (
+	EventRenewed       EventKind = "renewed"
+	EventRenewalFailed EventKind = "renewal_failed"
+	EventSkippedIdle   EventKind = "skipped_idle"
+	// EventTickFailed is sent by Run when a pass fails as a whole, e.g.
+	// because the consumers cannot be listed. Consumer is zero.
+	EventTickFailed EventKind = "tick_failed"
+)
+
+// Event reports what the scheduler did with a consumer.
+type Event struct {
+	Kind     EventKind
+	Consumer trenergy.Consumer
+	// Renewed is the consumer created by MethodBootstrap, or the updated
+	// consumer for MethodAutoRenewal.
+	Renewed *trenergy.Consumer
+	// Err is set for EventRenewalFailed and EventTickFailed, and for
+	// EventRenewed when the old consumer could not be deactivated after
+	// MethodBootstrap.
+	Err error
+	At  time.Time
+}
+
+func (e Event) String() string {
+	s := string(e.Kind)
+	if e.Consumer.ID != 0 {
+		s += fmt.Sprintf(" consumer %d (%s)", e.Consumer.ID, e.Consumer.Address)
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// IdlePolicy reports whether a consumer has been idle and should not be
+// renewed. summary is fetched once per pass.
+type IdlePolicy func(c trenergy.Consumer, summary *trenergy.ConsumersSummary) bool
+
+// IdleBySummary is a coarse, account-wide check: it treats a consumer as idle
+// when the summary reports no consumption by any active consumer of its
+// resource. It cannot tell consumers apart, so one busy consumer keeps every
+// idle one of the same resource renewed, and a consumer whose usage fell in
+// a quiet moment of the whole account is skipped. Pass your own IdlePolicy
+// to judge each consumer separately.
+func IdleBySummary(c trenergy.Consumer, summary *trenergy.ConsumersSummary) bool {
+	if c.Resource == trenergy.ResourceBandwidth {
+		return summary.ActiveBandwidthConsumption == 0
+	}
+	return summary.ActiveEnergyConsumption == 0
+}
+
+// Scheduler renews orders before they expire.
+type Scheduler struct {
+	client     *trenergy.Client
+	lead       time.Duration
+	interval   time.Duration
+	maxBackoff time.Duration
+	method     Method
+	store      Store
+	idle       IdlePolicy
+	onEvent    func(Event)
+	now        func() time.Time
+}
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithLeadTime sets how long before ValidUntil an order is renewed.
+// The default is 5 minutes.
+func WithLeadTime(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.lead = d
+	}
+}
+
+// WithInterval sets how often Run checks the consumers. The default is one minute.
+func WithInterval(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.interval = d
+	}
+}
+
+// WithMethod sets how orders are renewed. The default is MethodBootstrap.
+func WithMethod(m Method) Option {
+	return func(s *Scheduler) {
+		s.method = m
+	}
+}
+
+// WithStore sets where records are persisted. The default is a MemoryStore.
+func WithStore(store Store) Option {
+	return func(s *Scheduler) {
+		s.store = store
+	}
+}
+
+// WithIdlePolicy skips consumers for which p reports true. Without it, idle
+// detection is off and every due consumer is renewed.
+func WithIdlePolicy(p IdlePolicy) Option {
+	return func(s *Scheduler) {
+		s.idle = p
+	}
+}
+
+// WithEventHandler receives an Event for every renewal, failure and skip,
+// and for every pass of Run that fails.
+func WithEventHandler(fn func(Event)) Option {
+	return func(s *Scheduler) {
+		s.onEvent = fn
+	}
+}
+
+// WithClock overrides the clock, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(s *Scheduler) {
+		s.now = now
+	}
+}
+
+// New creates a Scheduler.
+func New(client *trenergy.Client, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		client:     client,
+		lead:       5 * time.Minute,
+		interval:   time.Minute,
+		maxBackoff: time.Hour,
+		store:      NewMemoryStore(),
+		idle:       nil, // idle detection is off unless WithIdlePolicy is given
+		onEvent:    func(Event) {},
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run calls Tick every interval until ctx is done and returns the context's
+// error. An error from Tick is sent to the event handler as an
+// EventTickFailed, and the pass is retried on the next tick.
+func (s *Scheduler) Run(ctx context.Context) error {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		if err := s.Tick(ctx); err != nil && ctx.Err() == nil {
+			s.onEvent(Event{Kind: EventTickFailed, Err: err, At: s.now()})
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Tick makes one pass over the consumers and renews those that are due.
+// It returns an error if the consumers or the records cannot be read;
+// renewal failures are reported as events and retried with backoff.
+func (s *Scheduler) Tick(ctx context.Context) error {
+	consumers, err := trenergy.CollectAll(s.client.AllConsumers(ctx), 0)
+	if err != nil {
+		return fmt.Errorf("renewal: listing consumers: %w", err)
+	}
+	records, err := s.store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("renewal: loading records: %w", err)
+	}
+
+	var summary *trenergy.ConsumersSummary
+	now := s.now()
+	seen := make(map[int]bool, len(consumers))
+	var errs []error
+	for _, c := range consumers {
+		seen[c.ID] = true
+		rec, ok := records[c.ID]
+		if !ok {
+			rec = Record{ConsumerID: c.ID, Address: c.Address}
+		}
+		if !s.due(c, rec, now) {
+			continue
+		}
+
+		if s.idle != nil {
+			if summary == nil {
+				resp, err := s.client.GetConsumersSummary(ctx)
+				if err != nil {
+					return fmt.Errorf("renewal: fetching summary: %w", err)
+				}
+				summary = resp.Data
+			}
+			if s.idle(c, summary) {
+				s.onEvent(Event{Kind: EventSkippedIdle, Consumer: c, At: now})
+				continue
+			}
+		}
+
+		renewed, err := s.renew(ctx, c)
+		if err != nil {
+			rec.Failures++
+			rec.LastError = err.Error()
+			rec.NextAttempt = now.Add(s.backoff(rec.Failures))
+			s.onEvent(Event{Kind: EventRenewalFailed, Consumer: c, Err: err, At: now})
+		} else {
+			rec.RenewedFor = c.Order.ValidUntil.Time
+			rec.LastRenewal = now
+			rec.Failures = 0
+			rec.LastError = ""
+			rec.NextAttempt = time.Time{}
+			var retireErr error
+			if s.method == MethodBootstrap {
+				retireErr = s.retire(ctx, c)
+			}
+			s.onEvent(Event{Kind: EventRenewed, Consumer: c, Renewed: renewed, Err: retireErr, At: now})
+		}
+		if err := s.store.Save(ctx, rec); err != nil {
+			errs = append(errs, fmt.Errorf("renewal: saving record %d: %w", c.ID, err))
+		}
+	}
+
+	for id := range records {
+		if !seen[id] {
+			if err := s.store.Delete(ctx, id); err != nil {
+				errs = append(errs, fmt.Errorf("renewal: deleting record %d: %w", id, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// due reports whether c should be renewed now.
+func (s *Scheduler) due(c trenergy.Consumer, rec Record, now time.Time) bool {
+	if c.AutoRenewal || !c.IsActive || c.Order == nil || c.Order.ValidUntil.IsZero() {
+		return false
+	}
+	validUntil := c.Order.ValidUntil.Time
+	if now.Before(validUntil.Add(-s.lead)) {
+		return false
+	}
+	if rec.RenewedFor.Equal(validUntil) {
+		return false
+	}
+	return !now.Before(rec.NextAttempt)
+}
+
+func (s *Scheduler) renew(ctx context.Context, c trenergy.Consumer) (*trenergy.Consumer, error) {
+	if s.method == MethodAutoRenewal {
+		resp, err := s.client.UpdateConsumer(ctx, c.ID, trenergy.UpdateConsumerParams{AutoRenewal: trenergy.Ptr(true)})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Data, nil
+	}
+
+	amount := c.DesiredResourceAmount
+	if !amount.Valid {
+		amount = c.ResourceAmount
+	}
+	resp, err := s.client.CreateBootstrapOrder(ctx, trenergy.ConsumerParams{
+		Address:        c.Address,
+		PaymentPeriod:  c.PaymentPeriod,
+		ResourceAmount: amount.Value,
+		Name:           c.Name,
+		Resource:       c.Resource,
+		WebhookURL:     c.WebhookURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// retire deactivates a consumer replaced by MethodBootstrap. The record of
+// the renewal is saved either way, so a failure does not renew it again.
+func (s *Scheduler) retire(ctx context.Context, c trenergy.Consumer) error {
+	if _, err := s.client.DeactivateConsumer(ctx, c.ID); err != nil {
+		return fmt.Errorf("deactivating replaced consumer: %w", err)
+	}
+	return nil
+}
+
+// backoff returns the delay before the next attempt after n failures.
+func (s *Scheduler) backoff(n int) time.Duration {
+	d := s.interval
+	for i := 1; i < n && d < s.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, s.maxBackoff)
+}
@@ -0,0 +1,184 @@
+package renewal_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/renewal"
+	"github.com/cyvadra/trenergy/trenergytest"
+)
+
+const testAddress = "TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF"
+
+// clock is a manual clock shared by the fake server and the scheduler.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func setup(t *testing.T) (*trenergytest.Server, *trenergy.Client, *clock) {
+	t.Helper()
+	clk := &clock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100*trenergy.OneTRX), trenergytest.WithClock(clk.Now))
+	t.Cleanup(fake.Close)
+	client := fake.NewClient()
+
+	_, err := client.CreateBootstrapOrder(context.Background(), trenergy.ConsumerParams{
+		Address:        testAddress,
+		PaymentPeriod:  trenergy.PaymentPeriod15M,
+		ResourceAmount: 1000,
+		Resource:       trenergy.ResourceEnergy,
+	})
+	if err != nil {
+		t.Fatalf("CreateBootstrapOrder failed: %v", err)
+	}
+	return fake, client, clk
+}
+
+func TestSchedulerRenewsBeforeExpiry(t *testing.T) {
+	fake, client, clk := setup(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "renewals.json")
+
+	var events []renewal.Event
+	s := renewal.New(client,
+		renewal.WithLeadTime(5*time.Minute),
+		renewal.WithStore(renewal.NewFileStore(path)),
+		renewal.WithClock(clk.Now),
+		renewal.WithEventHandler(func(e renewal.Event) { events = append(events, e) }))
+
+	clk.Advance(5 * time.Minute)
+	if err := s.Tick(ctx); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("renewed too early: %v", events)
+	}
+
+	clk.Advance(6 * time.Minute)
+	if err := s.Tick(ctx); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != renewal.EventRenewed || events[0].Renewed == nil {
+		t.Fatalf("expected one renewal, got %v", events)
+	}
+	consumers := fake.Consumers()
+	if len(consumers) != 2 || consumers[0].IsActive || !consumers[1].IsActive {
+		t.Fatalf("expected the old consumer to be replaced, got %+v", consumers)
+	}
+
+	// A restarted scheduler reads the store and does not renew again.
+	restarted := renewal.New(client,
+		renewal.WithLeadTime(5*time.Minute),
+		renewal.WithStore(renewal.NewFileStore(path)),
+		renewal.WithClock(clk.Now),
+		renewal.WithEventHandler(func(e renewal.Event) { events = append(events, e) }))
+	if err := restarted.Tick(ctx); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if len(events) != 1 || len(fake.Consumers()) != 2 {
+		t.Errorf("renewed twice: %v", events)
+	}
+}
+
+func TestSchedulerBacksOffAfterFailure(t *testing.T) {
+	fake, client, clk := setup(t)
+	ctx := context.Background()
+
+	var events []renewal.Event
+	s := renewal.New(client,
+		renewal.WithInterval(time.Minute),
+		renewal.WithClock(clk.Now),
+		renewal.WithEventHandler(func(e renewal.Event) { events = append(events, e) }))
+
+	clk.Advance(12 * time.Minute)
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers/bootstrap-order", Status: 503})
+	if err := s.Tick(ctx); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != renewal.EventRenewalFailed {
+		t.Fatalf("expected a failure event, got %v", events)
+	}
+
+	if err := s.Tick(ctx); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected no attempt during backoff, got %v", events)
+	}
+
+	clk.Advance(time.Minute)
+	if err := s.Tick(ctx); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if len(events) != 2 || events[1].Kind != renewal.EventRenewed {
+		t.Fatalf("expected a renewal after backoff, got %v", events)
+	}
+}
+
+func TestSchedulerAutoRenewalAndIdle(t *testing.T) {
+	fake, client, clk := setup(t)
+	ctx := context.Background()
+	clk.Advance(12 * time.Minute)
+
+	var events []renewal.Event
+	record := func(e renewal.Event) { events = append(events, e) }
+
+	idle := renewal.New(client, renewal.WithClock(clk.Now), renewal.WithEventHandler(record),
+		renewal.WithIdlePolicy(renewal.IdleBySummary))
+	if err := idle.Tick(ctx); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != renewal.EventSkippedIdle {
+		t.Fatalf("expected the idle consumer to be skipped, got %v", events)
+	}
+
+	s := renewal.New(client, renewal.WithClock(clk.Now), renewal.WithEventHandler(record),
+		renewal.WithMethod(renewal.MethodAutoRenewal))
+	if err := s.Tick(ctx); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	consumers := fake.Consumers()
+	if len(consumers) != 1 || !consumers[0].AutoRenewal {
+		t.Errorf("expected auto-renewal to be enabled, got %+v", consumers)
+	}
+	if len(events) != 2 || events[1].Kind != renewal.EventRenewed {
+		t.Errorf("expected a renewal event, got %v", events)
+	}
+}
+
+func TestRunReportsTickErrors(t *testing.T) {
+	fake, client, clk := setup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fake.InjectFailure(trenergytest.Failure{Path: "/api/consumers", Status: 403})
+	var events []renewal.Event
+	s := renewal.New(client, renewal.WithClock(clk.Now), renewal.WithInterval(time.Millisecond),
+		renewal.WithEventHandler(func(e renewal.Event) {
+			events = append(events, e)
+			cancel()
+		}))
+	if err := s.Run(ctx); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != renewal.EventTickFailed || events[0].Err == nil {
+		t.Errorf("expected a failed pass, got %v", events)
+	}
+}
@@ -0,0 +1,156 @@
+package renewal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Record is the scheduler's state for one consumer.
+type Record struct {
+	ConsumerID int    `json:"consumer_id"`
+	Address    string `json:"address"`
+	// RenewedFor is the ValidUntil of the order that was last renewed, so
+	// that the same order is not renewed twice.
+	RenewedFor  time.Time `json:"renewed_for,omitzero"`
+	LastRenewal time.Time `json:"last_renewal,omitzero"`
+	// Failures counts consecutive failed attempts.
+	Failures    int       `json:"failures,omitempty"`
+	NextAttempt time.Time `json:"next_attempt,omitzero"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Store persists records so that a restarted scheduler does not renew an
+// order twice or forget its backoff.
+type Store interface {
+	Load(ctx context.Context) (map[int]Record, error)
+	Save(ctx context.Context, r Record) error
+	Delete(ctx context.Context, consumerID int) error
+}
+
+// MemoryStore is a Store that keeps records in memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[int]Record
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[int]Record)}
+}
+
+func (m *MemoryStore) Load(context.Context) (map[int]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.records), nil
+}
+
+func (m *MemoryStore) Save(_ context.Context, r Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[r.ConsumerID] = r
+	return nil
+}
+
+func (m *MemoryStore) Delete(_ context.Context, consumerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, consumerID)
+	return nil
+}
+
+// FileStore is a Store backed by a JSON file, rewritten atomically on every
+// change.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+	mem  *MemoryStore
+}
+
+// NewFileStore returns a FileStore for path. The file is created on the
+// first save.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (f *FileStore) Load(ctx context.Context) (map[int]Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f.mem.Load(ctx)
+}
+
+func (f *FileStore) Save(ctx context.Context, r Record) error {
+	return f.update(func(m *MemoryStore) error { return m.Save(ctx, r) })
+}
+
+func (f *FileStore) Delete(ctx context.Context, consumerID int) error {
+	return f.update(func(m *MemoryStore) error { return m.Delete(ctx, consumerID) })
+}
+
+// load reads the file once.
+func (f *FileStore) load() error {
+	if f.mem != nil {
+		return nil
+	}
+	mem := NewMemoryStore()
+	data, err := os.ReadFile(f.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		var records []Record
+		if err := json.Unmarshal(data, &records); err != nil {
+			return err
+		}
+		for _, r := range records {
+			mem.records[r.ConsumerID] = r
+		}
+	}
+	f.mem = mem
+	return nil
+}
+
+func (f *FileStore) update(fn func(*MemoryStore) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.load(); err != nil {
+		return err
+	}
+	if err := fn(f.mem); err != nil {
+		return err
+	}
+
+	records := make([]Record, 0, len(f.mem.records))
+	for _, id := range slices.Sorted(maps.Keys(f.mem.records)) {
+		records = append(records, f.mem.records[id])
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
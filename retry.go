@@ -0,0 +1,203 @@
+package trenergy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how Client.Do retries failed requests.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. It doubles on every
+	// subsequent attempt, up to MaxBackoff, and is jittered.
+	MinBackoff time.Duration
+	// MaxBackoff also caps the delay a server requests with Retry-After,
+	// so that a large value cannot stall a call. If zero, Retry-After is
+	// capped at DefaultRetryPolicy.MaxBackoff.
+	MaxBackoff time.Duration
+	// RetryMutating allows retrying non-idempotent calls such as
+	// CreateBootstrapOrder, MassTrx or CreateWithdrawal. Only enable it if
+	// a duplicated request is acceptable.
+	RetryMutating bool
+	// RetryStatuses lists the HTTP statuses that are retried.
+	// If empty, 429, 502, 503 and 504 are retried.
+	RetryStatuses []int
+}
+
+// DefaultRetryPolicy retries idempotent requests up to three times.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  10 * time.Second,
+}
+
+var defaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// WithRetryPolicy enables automatic retries of transient failures.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// allows reports whether req may be retried under this policy.
+func (p RetryPolicy) allows(req *http.Request) bool {
+	if p.MaxAttempts < 2 {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// The body cannot be replayed.
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return p.RetryMutating
+}
+
+func (p RetryPolicy) retryStatus(code int) bool {
+	statuses := p.RetryStatuses
+	if len(statuses) == 0 {
+		statuses = defaultRetryStatuses
+	}
+	for _, s := range statuses {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the jittered delay before the given retry (starting at 1).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MinBackoff
+	if d <= 0 {
+		d = DefaultRetryPolicy.MinBackoff
+	}
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			d = p.MaxBackoff
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	// Equal jitter: wait between d/2 and d.
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// serverDelay returns the delay requested by a Retry-After header, capped at
+// MaxBackoff.
+func (p RetryPolicy) serverDelay(h http.Header) (time.Duration, bool) {
+	d, ok := retryAfter(h)
+	if !ok {
+		return 0, false
+	}
+	limit := p.MaxBackoff
+	if limit <= 0 {
+		limit = DefaultRetryPolicy.MaxBackoff
+	}
+	return min(d, limit), true
+}
+
+// retryAfter parses the Retry-After header, either in seconds or as an HTTP date.
+func retryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			secs = 0
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// doWithRetry sends req, retrying transient failures according to the client's policy.
+func (c *Client) doWithRetry(req *http.Request) (*http.Response, error) {
+	policy := c.retry
+	if !policy.allows(req) {
+		return c.send(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(req)
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return resp, err
+			}
+			wait = policy.backoff(attempt)
+		case policy.retryStatus(resp.StatusCode):
+			if d, ok := policy.serverDelay(resp.Header); ok {
+				wait = d
+			} else {
+				wait = policy.backoff(attempt)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+
+		next := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			next.Body = body
+		}
+		req = next
+	}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
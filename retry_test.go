@@ -0,0 +1,108 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cyvadra/trenergy"
+)
+
+var fastRetry = trenergy.RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond,
+	MaxBackoff:  5 * time.Millisecond,
+}
+
+func TestRetryIdempotentGet(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status":true,"data":{"name":"tester"}}`))
+	}))
+	defer srv.Close()
+
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL), trenergy.WithRetryPolicy(fastRetry))
+	resp, err := client.GetAccountInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetAccountInfo failed: %v", err)
+	}
+	if resp.Data.Name != "tester" {
+		t.Errorf("unexpected name %q", resp.Data.Name)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryMutatingRequiresOptIn(t *testing.T) {
+	var calls int32
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"status":true,"data":{"id":7}}`))
+	}))
+	defer srv.Close()
+
+	params := trenergy.ConsumerParams{Address: "TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF", PaymentPeriod: 60, ResourceAmount: 65000, Resource: 1}
+
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL), trenergy.WithRetryPolicy(fastRetry))
+	_, err := client.CreateBootstrapOrder(context.Background(), params)
+	var apiErr *trenergy.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502 without retry, got %v", err)
+	}
+
+	calls = 0
+	bodies = nil
+	policy := fastRetry
+	policy.RetryMutating = true
+	client = trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL), trenergy.WithRetryPolicy(policy))
+	resp, err := client.CreateBootstrapOrder(context.Background(), params)
+	if err != nil {
+		t.Fatalf("CreateBootstrapOrder failed: %v", err)
+	}
+	if resp.Data.ID != 7 {
+		t.Errorf("unexpected id %d", resp.Data.ID)
+	}
+	if len(bodies) != 2 || bodies[0] == "" || bodies[0] != bodies[1] {
+		t.Errorf("expected the multipart body to be replayed, got %q", bodies)
+	}
+}
+
+func TestRetryAfterCappedAtMaxBackoff(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"status":true,"data":{"name":"tester"}}`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL), trenergy.WithRetryPolicy(fastRetry))
+	if _, err := client.GetAccountInfo(ctx); err != nil {
+		t.Fatalf("expected the hour-long Retry-After to be capped, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
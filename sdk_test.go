@@ -2,16 +2,58 @@ package trenergy_test
 
 import (
 	"context"
-	"strings"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/trenergytest"
 )
 
-// Tests now run against TestNet as requested.
+// Tests run against a trenergytest.Server unless one of these is set:
+//
+//	TRENERGY_LIVE=1    call Nile directly
+//	TRENERGY_RECORD=1  call Nile and record cassettes in testdata/
+//	TRENERGY_REPLAY=1  replay the cassettes in testdata/; a missing one fails
+
+// newTestClient returns a client for the mode selected by the environment,
+// or for a fake server when none is.
+func newTestClient(t *testing.T) *trenergy.Client {
+	t.Helper()
+	var mode trenergytest.Mode
+	switch {
+	case os.Getenv("TRENERGY_LIVE") != "":
+		return trenergy.NewClient("", trenergy.WithTestNet())
+	case os.Getenv("TRENERGY_RECORD") != "":
+		mode = trenergytest.ModeRecord
+	case os.Getenv("TRENERGY_REPLAY") != "":
+		mode = trenergytest.ModeReplay
+	default:
+		fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+		t.Cleanup(fake.Close)
+		return fake.NewClient()
+	}
+
+	path := filepath.Join("testdata", t.Name()+".json")
+	rec, err := trenergytest.NewRecorder(path, mode, nil)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("no cassette %s; run with TRENERGY_RECORD=1 to record it", path)
+	}
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := rec.Save(); err != nil {
+			t.Errorf("saving cassette failed: %v", err)
+		}
+	})
+	return trenergy.NewClient("", trenergy.WithTestNet(), trenergy.WithHTTPClient(rec.Client()))
+}
 
 func TestGetAccountInfo(t *testing.T) {
-	client := trenergy.NewClient("", trenergy.WithTestNet())
+	client := newTestClient(t)
 	ctx := context.Background()
 
 	resp, err := client.GetAccountInfo(ctx)
@@ -29,7 +71,7 @@ func TestGetAccountInfo(t *testing.T) {
 }
 
 func TestCreateBootstrapOrder(t *testing.T) {
-	client := trenergy.NewClient("", trenergy.WithTestNet())
+	client := newTestClient(t)
 	ctx := context.Background()
 
 	// Use a valid format address but maybe one that is reusable or temporary.
@@ -45,9 +87,9 @@ func TestCreateBootstrapOrder(t *testing.T) {
 		// Let's assume 1 (1 hour) is valid or strict to 15?
 		// "usage: 15, 60, etc." comment in code. Let's use 1 if allowed or 15.
 		// NOTE: TestNet might have diff rules. Let's try 1.
-		PaymentPeriod: 60, // 1 hour (60 minutes)
+		PaymentPeriod: trenergy.PaymentPeriod1H,
 		AutoRenewal:   false,
-		Resource:      1, // Energy
+		Resource:      trenergy.ResourceEnergy,
 	}
 
 	// We can't guarantee this succeeds without funding or limits, but let's try.
@@ -64,20 +106,13 @@ func TestCreateBootstrapOrder(t *testing.T) {
 		t.Fatalf("CreateBootstrapOrder failed: %v", err)
 	}
 
-	if !resp.Status {
-		// It might fail logic, but we want to see if we reached the server.
-		t.Logf("CreateBootstrapOrder returned status false: %+v", resp)
-		// It is acceptable for testnet to fail if params are invalid, but we successfully hit the endpoint.
-		// However, "Use testnet for all tests" implies valid tests.
-	} else {
-		if resp.Data.ID == 0 {
-			t.Error("Expected valid ID, got 0")
-		}
+	if resp.Data.ID == 0 {
+		t.Error("Expected valid ID, got 0")
 	}
 }
 
 func TestActivateAddress(t *testing.T) {
-	client := trenergy.NewClient("", trenergy.WithTestNet())
+	client := newTestClient(t)
 	ctx := context.Background()
 
 	// Use the same address or another one.
@@ -85,32 +120,22 @@ func TestActivateAddress(t *testing.T) {
 	// Let's assume idempotency or we accept error "already active".
 	address := "TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF"
 
-	resp, err := client.ActivateAddress(ctx, address)
+	_, err := client.ActivateAddress(ctx, address)
 	if err != nil {
-		if isNotFundsError(err) {
+		// "status": false is surfaced as *BusinessError, e.g. "Already active".
+		var bizErr *trenergy.BusinessError
+		if isNotFundsError(err) || errors.As(err, &bizErr) {
 			t.Logf("ActivateAddress failed with expected business error: %v", err)
 			return
 		}
 		t.Fatalf("ActivateAddress failed: %v", err)
 	}
-
-	if !resp.Status {
-		// Log but don't fail if it's just business logic like "Already active"
-		// Actually, we should check errors.
-		// The API wrapper returns error for HTTP non-200.
-		// If 200 OK but status: false, then it's business error.
-		t.Logf("ActivateAddress status false (maybe already active?): %+v", resp)
-	}
 }
 
 func isNotFundsError(err error) bool {
-	return err != nil && (contains(err.Error(), "Not enough funds") || contains(err.Error(), "insufficient balance"))
+	return errors.Is(err, trenergy.ErrInsufficientFunds)
 }
 
 func isInvalidPaymentPeriodError(err error) bool {
-	return err != nil && contains(err.Error(), "payment_period is invalid")
-}
-
-func contains(s, substr string) bool {
-	return strings.Contains(s, substr)
+	return errors.Is(err, trenergy.ErrInvalidPaymentPeriod)
 }
@@ -3,19 +3,25 @@ package trenergy
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // Stake represents a stake record.
 type Stake struct {
-	ID          int     `json:"id"`
-	Resource    string  `json:"resource"`
-	TrxAmount   float64 `json:"trx_amount"` // Sample has it as number.
-	Type        int     `json:"type"`
-	IsCloses    bool    `json:"is_closes"`
-	ClosesAt    *string `json:"closes_at"`
-	DefrostedAt *string `json:"defrosted_at"`
-	CreatedAt   string  `json:"created_at"`
-	AvailableAt string  `json:"available_at"`
+	ID          int        `json:"id"`
+	Resource    Resource   `json:"resource"`
+	TrxAmount   TRX        `json:"trx_amount"`
+	Type        int        `json:"type"`
+	IsCloses    bool       `json:"is_closes"`
+	ClosesAt    *Timestamp `json:"closes_at"`
+	DefrostedAt *Timestamp `json:"defrosted_at"`
+	CreatedAt   Timestamp  `json:"created_at"`
+	AvailableAt Timestamp  `json:"available_at"`
+}
+
+// IsAvailable reports whether the stake can be unstaked at now.
+func (s *Stake) IsAvailable(now time.Time) bool {
+	return !s.IsCloses && !now.Before(s.AvailableAt.Time)
 }
 
 // ListStakes retrieves a list of stakes.
@@ -31,42 +37,39 @@ func (c *Client) ListStakes(ctx context.Context, page int) (*APIResponse[[]Stake
 
 // CreateStakeParams
 type CreateStakeParams struct {
-	TrxAmount float64
+	TrxAmount TRX `form:"trx_amount"`
 }
 
 // CreateStake creates a new stake.
 func (c *Client) CreateStake(ctx context.Context, params CreateStakeParams) (*APIResponse[struct{}], error) {
-	data := make(map[string]string)
-	data["trx_amount"] = fmt.Sprintf("%f", params.TrxAmount)
-
+	spend, err := c.reserve(ctx, "stake", params.TrxAmount, 0)
+	if err != nil {
+		return nil, err
+	}
 	var resp APIResponse[struct{}]
-	err := c.postMultipart(ctx, "/api/stakes", data, &resp)
+	err = c.sendForm(ctx, "POST", "/api/stakes", bodyMultipart, params, &resp)
 	if err != nil {
+		spend.fail(ctx, err)
 		return nil, err
 	}
+	spend.commit(ctx, params.TrxAmount)
 	return &resp, nil
 }
 
 // UnstakeParams
 type UnstakeParams struct {
-	TrxAmount float64
-	OTP       string
+	TrxAmount TRX    `form:"trx_amount"`
+	OTP       string `form:"one_time_password,omitempty"`
 }
 
 // Unstake unstakes an amount.
 func (c *Client) Unstake(ctx context.Context, params UnstakeParams) (*APIResponse[struct {
-	UnstakeDate string `json:"unstake_date"`
+	UnstakeDate Timestamp `json:"unstake_date"`
 }], error) {
-	data := make(map[string]string)
-	data["trx_amount"] = fmt.Sprintf("%f", params.TrxAmount)
-	if params.OTP != "" {
-		data["one_time_password"] = params.OTP
-	}
-
 	var resp APIResponse[struct {
-		UnstakeDate string `json:"unstake_date"`
+		UnstakeDate Timestamp `json:"unstake_date"`
 	}]
-	err := c.postMultipart(ctx, "/api/stakes/unstake", data, &resp)
+	err := c.sendForm(ctx, "POST", "/api/stakes/unstake", bodyMultipart, params, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -85,8 +88,8 @@ func (c *Client) SyncStakes(ctx context.Context) (*APIResponse[struct{}], error)
 
 // StakeProfitabilityItem represents profitability data point.
 type StakeProfitabilityItem struct {
-	Received float64 `json:"received"`
-	Date     string  `json:"date"`
+	Received TRX       `json:"received"`
+	Date     Timestamp `json:"date"`
 }
 
 // GetStakeProfitability retrieves stake profitability info.
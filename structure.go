@@ -13,17 +13,17 @@ type Partner struct {
 
 // PartnerUser represents a user in the partner structure.
 type PartnerUser struct {
-	ID                            int     `json:"id"`
-	Name                          string  `json:"name"`
-	Photo                         string  `json:"photo"`
-	LeaderLevel                   int     `json:"leader_level"`
-	LevelName                     string  `json:"level_name"`
-	LeaderID                      *int    `json:"leader_id"`
-	Stake                         float64 `json:"stake"`
-	ActiveStakersCount            int     `json:"active_stakers_count"`
-	TotalStakesInStructure        float64 `json:"total_stakes_in_structure"`
-	TotalActiveStakersInStructure int     `json:"total_active_stakers_in_structure"`
-	TotalPartnersInStructure      int     `json:"total_partners_in_structure"`
+	ID                            int    `json:"id"`
+	Name                          string `json:"name"`
+	Photo                         string `json:"photo"`
+	LeaderLevel                   int    `json:"leader_level"`
+	LevelName                     string `json:"level_name"`
+	LeaderID                      *int   `json:"leader_id"`
+	Stake                         TRX    `json:"stake"`
+	ActiveStakersCount            int    `json:"active_stakers_count"`
+	TotalStakesInStructure        TRX    `json:"total_stakes_in_structure"`
+	TotalActiveStakersInStructure int    `json:"total_active_stakers_in_structure"`
+	TotalPartnersInStructure      int    `json:"total_partners_in_structure"`
 }
 
 // GetPartners retrieves the partner structure.
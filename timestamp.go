@@ -0,0 +1,75 @@
+package trenergy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Timestamp is a time decoded from any of the formats the API emits:
+// ISO-8601 with or without zone and fractional seconds, "Y-m-d H:i:s" and
+// "Y-m-d". Times without a zone are taken as UTC. JSON null and "" decode to
+// the zero Timestamp, and the zero Timestamp encodes as null.
+type Timestamp struct {
+	time.Time
+}
+
+// timestampLayout is the layout used when encoding, matching the API.
+const timestampLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// timestampLayouts are tried in order when decoding.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.DateOnly,
+}
+
+// ParseTimestamp parses s using the formats accepted by Timestamp.
+func ParseTimestamp(s string) (Timestamp, error) {
+	if s == "" {
+		return Timestamp{}, nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return Timestamp{Time: t}, nil
+		}
+	}
+	return Timestamp{}, fmt.Errorf("trenergy: cannot parse timestamp %q", s)
+}
+
+// String formats the timestamp like the API does, or returns "" if it is zero.
+func (t Timestamp) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timestampLayout)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*t = Timestamp{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseTimestamp(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
@@ -0,0 +1,57 @@
+package trenergy_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cyvadra/trenergy"
+)
+
+func TestTimestampFormats(t *testing.T) {
+	want := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	for _, s := range []string{
+		`"2024-05-06T07:08:09.000000Z"`,
+		`"2024-05-06T10:08:09+03:00"`,
+		`"2024-05-06T07:08:09"`,
+		`"2024-05-06 07:08:09"`,
+	} {
+		var ts trenergy.Timestamp
+		if err := json.Unmarshal([]byte(s), &ts); err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if !ts.Equal(want) {
+			t.Errorf("%s: got %v", s, ts.Time)
+		}
+	}
+
+	var w trenergy.Withdrawal
+	if err := json.Unmarshal([]byte(`{"created_at":null,"updated_at":"2024-05-06"}`), &w); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !w.CreatedAt.IsZero() || w.UpdatedAt.Day() != 6 {
+		t.Errorf("unexpected timestamps %v %v", w.CreatedAt, w.UpdatedAt)
+	}
+
+	var bad trenergy.Timestamp
+	if err := json.Unmarshal([]byte(`"yesterday"`), &bad); err == nil {
+		t.Error("expected error for unparseable timestamp")
+	}
+}
+
+func TestOrderAndStakeHelpers(t *testing.T) {
+	now := time.Now()
+	order := trenergy.Order{ValidUntil: trenergy.Timestamp{Time: now.Add(time.Hour)}}
+	if d := order.ExpiresIn(); d <= 59*time.Minute || d > time.Hour {
+		t.Errorf("unexpected ExpiresIn %v", d)
+	}
+	if order.IsExpired(now) || !order.IsExpired(now.Add(2*time.Hour)) {
+		t.Error("unexpected IsExpired result")
+	}
+
+	stake := trenergy.Stake{AvailableAt: trenergy.Timestamp{Time: now}}
+	if !stake.IsAvailable(now) || stake.IsAvailable(now.Add(-time.Second)) {
+		t.Error("unexpected IsAvailable result")
+	}
+}
@@ -0,0 +1,265 @@
+package trenergytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder talks to the network.
+type Mode int
+
+const (
+	// ModeReplay answers requests from the cassette only.
+	ModeReplay Mode = iota
+	// ModeRecord forwards requests to the real transport and records them.
+	ModeRecord
+)
+
+// ErrNoInteraction is returned in replay mode when no recorded interaction
+// matches a request.
+var ErrNoInteraction = errors.New("trenergytest: no recorded interaction matches the request")
+
+// redacted replaces the value of sensitive headers in cassettes.
+const redacted = "REDACTED"
+
+// Cassette is the on-disk format of recorded interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request/response pair.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request used for matching.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  url.Values  `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	// Form holds urlencoded and multipart fields, independent of the
+	// multipart boundary.
+	Form url.Values `json:"form,omitempty"`
+	// Body holds any other body, e.g. JSON.
+	Body string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded response.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that records interactions to a JSON
+// cassette file or replays them from it. Plug it into the SDK with
+// trenergy.WithHTTPClient(rec.Client()).
+type Recorder struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// NewRecorder creates a Recorder for the cassette at path. In replay mode the
+// cassette must exist. In record mode requests are sent through next, or
+// http.DefaultTransport if next is nil, and Save must be called to write the
+// cassette.
+func NewRecorder(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{path: path, mode: mode, next: next}
+
+	if mode == ModeReplay {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &r.cassette); err != nil {
+			return nil, fmt.Errorf("trenergytest: cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Client returns an *http.Client using the Recorder as its transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Save writes the recorded interactions to the cassette file. It is a no-op
+// in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(b, '\n'), 0o644)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, body, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	out := req.Clone(req.Context())
+	if body != nil {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := r.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: redactHeader(resp.Header),
+			Body:   string(respBody),
+		},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// replay answers req with the first unused interaction matching it.
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || !in.Request.matches(recorded) {
+			continue
+		}
+		r.used[i] = true
+		header := in.Response.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL.RequestURI())
+}
+
+// matches compares method, path, query and the normalized body.
+func (want RecordedRequest) matches(got RecordedRequest) bool {
+	return want.Method == got.Method &&
+		want.Path == got.Path &&
+		valuesEqual(want.Query, got.Query) &&
+		valuesEqual(want.Form, got.Form) &&
+		want.Body == got.Body
+}
+
+func valuesEqual(a, b url.Values) bool {
+	return maps.EqualFunc(a, b, slices.Equal)
+}
+
+// recordRequest captures req for matching and returns its raw body so that
+// it can be forwarded.
+func recordRequest(req *http.Request) (RecordedRequest, []byte, error) {
+	rec := RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Header: redactHeader(req.Header),
+	}
+	if q := req.URL.Query(); len(q) > 0 {
+		rec.Query = q
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return rec, nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return rec, nil, err
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		rec.Form, err = url.ParseQuery(string(body))
+	case "multipart/form-data":
+		var form *multipart.Form
+		form, err = multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(32 << 20)
+		if err == nil {
+			rec.Form = url.Values(form.Value)
+		}
+	case "application/json":
+		var buf bytes.Buffer
+		if json.Compact(&buf, body) == nil {
+			rec.Body = buf.String()
+		} else {
+			rec.Body = string(body)
+		}
+	default:
+		rec.Body = string(body)
+	}
+	return rec, body, err
+}
+
+// redactHeader clones h and hides credentials.
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, key := range []string{"Authorization", "Cookie", "Set-Cookie"} {
+		if h.Get(key) != "" {
+			h.Set(key, redacted)
+		}
+	}
+	if len(h) == 0 {
+		return nil
+	}
+	return h
+}
@@ -0,0 +1,61 @@
+package trenergytest_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/trenergytest"
+)
+
+func TestRecorderRoundTrip(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := context.Background()
+	params := trenergy.ConsumerParams{Address: testAddress, PaymentPeriod: 60, ResourceAmount: 65000, Resource: 1}
+
+	rec, err := trenergytest.NewRecorder(path, trenergytest.ModeRecord, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	client := trenergy.NewClient(fake.APIKey, trenergy.WithBaseURL(fake.URL), trenergy.WithHTTPClient(rec.Client()))
+	created, err := client.CreateBootstrapOrder(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateBootstrapOrder failed: %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	fake.Close()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if strings.Contains(string(raw), fake.APIKey) {
+		t.Error("cassette leaks the API key")
+	}
+
+	// The server is gone; the multipart boundary differs on replay.
+	rec, err = trenergytest.NewRecorder(path, trenergytest.ModeReplay, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	client = trenergy.NewClient(fake.APIKey, trenergy.WithBaseURL(fake.URL), trenergy.WithHTTPClient(rec.Client()))
+	replayed, err := client.CreateBootstrapOrder(ctx, params)
+	if err != nil {
+		t.Fatalf("replayed CreateBootstrapOrder failed: %v", err)
+	}
+	if replayed.Data.ID != created.Data.ID {
+		t.Errorf("expected id %d, got %d", created.Data.ID, replayed.Data.ID)
+	}
+
+	params.ResourceAmount = 1
+	if _, err := client.CreateBootstrapOrder(ctx, params); !errors.Is(err, trenergytest.ErrNoInteraction) {
+		t.Errorf("expected ErrNoInteraction, got %v", err)
+	}
+}
@@ -0,0 +1,668 @@
+package trenergytest
+
+import (
+	"cmp"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cyvadra/trenergy"
+)
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /api/account", s.handle(s.getAccount))
+	mux.Handle("GET /api/account/top-up", s.handle(s.getTopUp))
+
+	mux.Handle("POST /api/consumers/bootstrap-order", s.handle(s.createBootstrapOrder))
+	mux.Handle("GET /api/consumers", s.handle(s.listConsumers))
+	mux.Handle("GET /api/consumers/summary", s.handle(s.consumersSummary))
+	mux.Handle("GET /api/consumers/{id}", s.handle(s.getConsumer))
+	mux.Handle("PATCH /api/consumers/{id}", s.handle(s.updateConsumer))
+	mux.Handle("DELETE /api/consumers/{id}", s.handle(s.deleteConsumer))
+	mux.Handle("POST /api/consumers/{id}/activate", s.handle(s.setConsumerActive(true)))
+	mux.Handle("POST /api/consumers/{id}/deactivate", s.handle(s.setConsumerActive(false)))
+	mux.Handle("GET /api/consumers/{id}/payments", s.handle(s.consumerPayments))
+	mux.Handle("POST /api/consumers/mass/payment-period", s.handle(s.massPaymentPeriod))
+	mux.Handle("POST /api/consumers/mass/trx", s.handle(s.massTrx))
+	mux.Handle("POST /api/extra/activate-address", s.handle(s.activateAddress))
+
+	mux.Handle("GET /api/aml", s.handle(s.listAMLChecks))
+	mux.Handle("POST /api/aml/check", s.handle(s.checkAML))
+	mux.Handle("GET /api/aml/{id}", s.handle(s.getAMLCheck))
+
+	mux.Handle("GET /api/stakes", s.handle(s.listStakes))
+	mux.Handle("POST /api/stakes", s.handle(s.createStake))
+	mux.Handle("POST /api/stakes/unstake", s.handle(s.unstake))
+	mux.Handle("POST /api/stakes/sync", s.handle(s.syncStakes))
+	mux.Handle("GET /api/stakes/profitability", s.handle(s.stakeProfitability))
+
+	mux.Handle("GET /api/wallets", s.handle(s.listWallets))
+	mux.Handle("POST /api/wallets", s.handle(s.addWallet))
+	mux.Handle("DELETE /api/wallets/{id}", s.handle(s.deleteWallet))
+
+	mux.Handle("GET /api/withdrawals", s.handle(s.listWithdrawals))
+	mux.Handle("POST /api/withdrawals", s.handle(s.createWithdrawal))
+
+	mux.Handle("GET /api/transactions/internal", s.handle(s.listTransactions))
+
+	mux.Handle("GET /api/structure/partners", s.handle(s.listPartners))
+
+	return mux
+}
+
+func (s *Server) timestamp() trenergy.Timestamp {
+	return s.at(0)
+}
+
+// at returns the current time plus d, rounded to microseconds like the API.
+func (s *Server) at(d time.Duration) trenergy.Timestamp {
+	return trenergy.Timestamp{Time: s.now().Add(d).UTC().Truncate(time.Microsecond)}
+}
+
+func (s *Server) newID() int {
+	id := s.nextID
+	s.nextID++
+	return id
+}
+
+// debit charges amount TRX to the account and records a transaction.
+func (s *Server) debit(amount trenergy.TRX, txType trenergy.TxType, txableID int) error {
+	if amount > s.account.Balance+s.account.CreditLimit {
+		return errInsufficientFunds()
+	}
+	s.account.Balance -= amount
+	s.record(-amount, txType, txableID)
+	return nil
+}
+
+func (s *Server) record(amount trenergy.TRX, txType trenergy.TxType, txableID int) {
+	s.transactions = append(s.transactions, trenergy.InternalTransaction{
+		ID:        s.newID(),
+		Amount:    amount,
+		Type:      txType,
+		Coin:      trenergy.CoinTRX,
+		TxableID:  txableID,
+		CreatedAt: s.timestamp(),
+	})
+}
+
+func validAddress(addr string) bool {
+	return trenergy.ValidateAddress(addr) == nil
+}
+
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, errNotFound()
+	}
+	return id, nil
+}
+
+func formTRX(r *http.Request, key string) (trenergy.TRX, error) {
+	v, err := trenergy.ParseTRX(r.FormValue(key))
+	if err != nil || v <= 0 {
+		return 0, errValidation(key, "The "+key+" field must be a positive number.")
+	}
+	return v, nil
+}
+
+func formBool(r *http.Request, key string) bool {
+	v := r.FormValue(key)
+	return v == "1" || v == "true"
+}
+
+// Account
+
+func (s *Server) getAccount(r *http.Request) (any, error) {
+	info := s.account
+	info.StakesSum = 0
+	for _, st := range s.stakes {
+		if !st.IsCloses {
+			info.StakesSum += st.TrxAmount
+		}
+	}
+	info.AvailableToUnstakeSum = info.StakesSum
+	return info, nil
+}
+
+func (s *Server) getTopUp(r *http.Request) (any, error) {
+	return trenergy.TopUpInfo{
+		Address:  "TKzxdSv2FZKQrEqkKVgp5DcwEXBEKMg2Ax",
+		TimeLeft: 3600,
+	}, nil
+}
+
+// Consumers
+
+func (s *Server) findConsumer(r *http.Request) (*trenergy.Consumer, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range s.consumers {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, errNotFound()
+}
+
+// orderCost returns the price in TRX of amount units for the given period.
+func (s *Server) orderCost(resource trenergy.Resource, period trenergy.PaymentPeriod, amount int64) (trenergy.TRX, bool) {
+	prices := s.pricesEnergy
+	if resource == trenergy.ResourceBandwidth {
+		prices = s.pricesBandwidth
+	}
+	priceSun, ok := prices[strconv.Itoa(int(period))]
+	if !ok {
+		return 0, false
+	}
+	return trenergy.TRX(math.Round(float64(amount) * priceSun)), true
+}
+
+func (s *Server) createBootstrapOrder(r *http.Request) (any, error) {
+	address := r.FormValue("address")
+	if !validAddress(address) {
+		return nil, errValidation("address", "The address is invalid.")
+	}
+	period, _ := strconv.Atoi(r.FormValue("payment_period"))
+	paymentPeriod := trenergy.PaymentPeriod(period)
+	amount, err := strconv.ParseInt(r.FormValue("resource_amount"), 10, 64)
+	if err != nil || amount <= 0 {
+		return nil, errValidation("resource_amount", "The resource_amount field is invalid.")
+	}
+	resource := trenergy.ResourceEnergy
+	if v := r.FormValue("resource"); v != "" {
+		n, _ := strconv.Atoi(v)
+		resource = trenergy.Resource(n)
+	}
+	if !resource.Valid() {
+		return nil, errValidation("resource", "The selected resource is invalid.")
+	}
+	cost, ok := s.orderCost(resource, paymentPeriod, amount)
+	if !ok {
+		return nil, errValidation("payment_period", "The selected payment_period is invalid.")
+	}
+
+	id := s.newID()
+	if err := s.debit(cost, trenergy.TxTypeOrderPayment, id); err != nil {
+		return nil, err
+	}
+
+	now := s.timestamp()
+	c := &trenergy.Consumer{
+		ID:                    id,
+		Name:                  r.FormValue("name"),
+		Address:               address,
+		Resource:              resource,
+		ResourceAmount:        trenergy.NewFlexInt(amount),
+		DesiredResourceAmount: trenergy.NewFlexInt(amount),
+		PaymentPeriod:         paymentPeriod,
+		AutoRenewal:           formBool(r, "auto_renewal"),
+		IsActive:              true,
+		WebhookURL:            r.FormValue("webhook_url"),
+		CreatedAt:             now,
+		UpdatedAt:             now,
+		EstimatedCostTrx:      cost,
+		Order: &trenergy.Order{
+			Status:               trenergy.OrderStatusCompleted,
+			CompletionPercentage: 100,
+			CreatedAt:            now,
+			UpdatedAt:            now,
+			ValidUntil:           s.at(paymentPeriod.Duration()),
+		},
+	}
+	s.consumers = append(s.consumers, c)
+	s.payments[id] = append(s.payments[id], trenergy.ConsumerPayment{
+		Amount:    cost,
+		Type:      trenergy.TxTypeOrderPayment,
+		Coin:      trenergy.CoinTRX,
+		Quantity:  trenergy.NewFlexInt(amount),
+		CreatedAt: now,
+	})
+	return c, nil
+}
+
+func (s *Server) listConsumers(r *http.Request) (any, error) {
+	q := r.URL.Query()
+	items := make([]trenergy.Consumer, 0, len(s.consumers))
+	for _, c := range s.consumers {
+		if address := q.Get("address"); address != "" && c.Address != address {
+			continue
+		}
+		if active := q.Get("is_active"); active != "" && c.IsActive != (active == "1" || active == "true") {
+			continue
+		}
+		if resource := q.Get("resource"); resource != "" && strconv.Itoa(int(c.Resource)) != resource {
+			continue
+		}
+		items = append(items, *c)
+	}
+
+	if sortBy := q.Get("sort_by"); sortBy != "" {
+		desc := q.Get("sort_direction") == "desc"
+		slices.SortStableFunc(items, func(a, b trenergy.Consumer) int {
+			var order int
+			if sortBy == "resource_amount" {
+				order = cmp.Compare(a.ResourceAmount.Value, b.ResourceAmount.Value)
+			} else {
+				order = a.CreatedAt.Compare(b.CreatedAt.Time)
+			}
+			if desc {
+				return -order
+			}
+			return order
+		})
+	}
+	return paginate(r, items), nil
+}
+
+func (s *Server) getConsumer(r *http.Request) (any, error) {
+	return s.findConsumer(r)
+}
+
+func (s *Server) updateConsumer(r *http.Request) (any, error) {
+	c, err := s.findConsumer(r)
+	if err != nil {
+		return nil, err
+	}
+	form := r.PostForm
+	var amount int64
+	if form.Has("resource_amount") {
+		amount, err = strconv.ParseInt(form.Get("resource_amount"), 10, 64)
+		if err != nil || amount <= 0 {
+			return nil, errValidation("resource_amount", "The resource_amount field is invalid.")
+		}
+	}
+	period, _ := strconv.Atoi(form.Get("payment_period"))
+	if form.Has("payment_period") {
+		if _, ok := s.pricesEnergy[strconv.Itoa(period)]; !ok {
+			return nil, errValidation("payment_period", "The selected payment_period is invalid.")
+		}
+	}
+
+	if form.Has("resource_amount") {
+		c.ResourceAmount = trenergy.NewFlexInt(amount)
+		c.DesiredResourceAmount = trenergy.NewFlexInt(amount)
+	}
+	if form.Has("payment_period") {
+		c.PaymentPeriod = trenergy.PaymentPeriod(period)
+	}
+	if form.Has("auto_renewal") {
+		c.AutoRenewal = formBool(r, "auto_renewal")
+	}
+	if form.Has("webhook_url") {
+		c.WebhookURL = form.Get("webhook_url")
+	}
+	if form.Has("name") {
+		c.Name = form.Get("name")
+	}
+	c.UpdatedAt = s.timestamp()
+	return c, nil
+}
+
+func (s *Server) deleteConsumer(r *http.Request) (any, error) {
+	c, err := s.findConsumer(r)
+	if err != nil {
+		return nil, err
+	}
+	s.consumers = slices.DeleteFunc(s.consumers, func(x *trenergy.Consumer) bool { return x == c })
+	return struct{}{}, nil
+}
+
+func (s *Server) setConsumerActive(active bool) handlerFunc {
+	return func(r *http.Request) (any, error) {
+		c, err := s.findConsumer(r)
+		if err != nil {
+			return nil, err
+		}
+		c.IsActive = active
+		c.UpdatedAt = s.timestamp()
+		return struct{}{}, nil
+	}
+}
+
+func (s *Server) consumerPayments(r *http.Request) (any, error) {
+	c, err := s.findConsumer(r)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(r, s.payments[c.ID]), nil
+}
+
+func (s *Server) consumersSummary(r *http.Request) (any, error) {
+	summary := trenergy.ConsumersSummary{
+		PeriodPricesEnergy:    s.pricesEnergy,
+		PeriodPricesBandwidth: s.pricesBandwidth,
+		AddressActivationFee:  s.addressActivationFee,
+	}
+	for _, c := range s.consumers {
+		energy := c.Resource == trenergy.ResourceEnergy
+		summary.TotalCount++
+		if energy {
+			summary.TotalEnergyCount++
+		} else {
+			summary.TotalBandwidthCount++
+		}
+		if c.IsActive {
+			summary.ActiveCount++
+			if energy {
+				summary.ActiveEnergyCount++
+			} else {
+				summary.ActiveBandwidthCount++
+			}
+		}
+	}
+	return summary, nil
+}
+
+// consumersByID resolves form ids to consumers, failing on the first unknown
+// id or when more ids than the batch limit are given.
+func (s *Server) consumersByID(r *http.Request, key string) ([]*trenergy.Consumer, error) {
+	ids := r.Form[key]
+	if len(ids) == 0 {
+		return nil, errValidation(key, "The "+key+" field is required.")
+	}
+	if s.massBatchLimit > 0 && len(ids) > s.massBatchLimit {
+		return nil, errValidation(key, "The "+key+" field must not have more than "+strconv.Itoa(s.massBatchLimit)+" items.")
+	}
+	var out []*trenergy.Consumer
+	for _, raw := range ids {
+		id, _ := strconv.Atoi(raw)
+		idx := slices.IndexFunc(s.consumers, func(c *trenergy.Consumer) bool { return c.ID == id })
+		if idx < 0 {
+			return nil, errValidation(key, "The selected "+key+" is invalid.")
+		}
+		out = append(out, s.consumers[idx])
+	}
+	return out, nil
+}
+
+func (s *Server) massPaymentPeriod(r *http.Request) (any, error) {
+	consumers, err := s.consumersByID(r, "consumer_ids[]")
+	if err != nil {
+		return nil, err
+	}
+	period, _ := strconv.Atoi(r.FormValue("payment_period"))
+	if _, ok := s.pricesEnergy[strconv.Itoa(period)]; !ok {
+		return nil, errValidation("payment_period", "The selected payment_period is invalid.")
+	}
+	now := s.timestamp()
+	for _, c := range consumers {
+		c.PaymentPeriod = trenergy.PaymentPeriod(period)
+		c.AutoRenewal = formBool(r, "auto_renewal")
+		c.UpdatedAt = now
+	}
+	return struct{}{}, nil
+}
+
+func (s *Server) massTrx(r *http.Request) (any, error) {
+	consumers, err := s.consumersByID(r, "consumers[]")
+	if err != nil {
+		return nil, err
+	}
+	amount, err := formTRX(r, "amount")
+	if err != nil {
+		return nil, err
+	}
+	if amount*trenergy.TRX(len(consumers)) > s.account.Balance+s.account.CreditLimit {
+		return nil, errInsufficientFunds()
+	}
+	now := s.timestamp()
+	for _, c := range consumers {
+		s.debit(amount, trenergy.TxTypeMassTrx, c.ID)
+		s.payments[c.ID] = append(s.payments[c.ID], trenergy.ConsumerPayment{
+			Amount:    amount,
+			Type:      trenergy.TxTypeMassTrx,
+			Coin:      trenergy.CoinTRX,
+			CreatedAt: now,
+		})
+	}
+	return struct{}{}, nil
+}
+
+func (s *Server) activateAddress(r *http.Request) (any, error) {
+	address := r.FormValue("address")
+	if !validAddress(address) {
+		return nil, errValidation("address", "The address is invalid.")
+	}
+	if s.activated[address] {
+		return nil, &apiError{status: http.StatusUnprocessableEntity, message: "Address is already activated"}
+	}
+	if err := s.debit(s.addressActivationFee, trenergy.TxTypeActivation, 0); err != nil {
+		return nil, err
+	}
+	s.activated[address] = true
+	return trenergy.ActivateAddressData{}, nil
+}
+
+// AML
+
+func (s *Server) listAMLChecks(r *http.Request) (any, error) {
+	return paginate(r, s.amlChecks), nil
+}
+
+func (s *Server) checkAML(r *http.Request) (any, error) {
+	address := r.FormValue("address")
+	if !validAddress(address) {
+		return nil, errValidation("address", "The address is invalid.")
+	}
+	check := trenergy.AMLCheck{
+		Address:   address,
+		Status:    "completed",
+		Context:   &trenergy.AMLCheckContext{Entities: []trenergy.AMLEntity{}},
+		CreatedAt: s.timestamp(),
+	}
+	if txid := r.FormValue("txid"); txid != "" {
+		check.TxID = &txid
+	}
+	s.amlChecks = append(s.amlChecks, check)
+	return check, nil
+}
+
+func (s *Server) getAMLCheck(r *http.Request) (any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	if id < 1 || id > len(s.amlChecks) {
+		return nil, errNotFound()
+	}
+	return s.amlChecks[id-1], nil
+}
+
+// Stakes
+
+func (s *Server) listStakes(r *http.Request) (any, error) {
+	return paginate(r, s.stakes), nil
+}
+
+func (s *Server) createStake(r *http.Request) (any, error) {
+	amount, err := formTRX(r, "trx_amount")
+	if err != nil {
+		return nil, err
+	}
+	id := s.newID()
+	if err := s.debit(amount, trenergy.TxTypeStake, id); err != nil {
+		return nil, err
+	}
+	s.stakes = append(s.stakes, trenergy.Stake{
+		ID:          id,
+		Resource:    trenergy.ResourceEnergy,
+		TrxAmount:   amount,
+		CreatedAt:   s.timestamp(),
+		AvailableAt: s.at(14 * 24 * time.Hour),
+	})
+	return struct{}{}, nil
+}
+
+func (s *Server) unstake(r *http.Request) (any, error) {
+	amount, err := formTRX(r, "trx_amount")
+	if err != nil {
+		return nil, err
+	}
+	var staked trenergy.TRX
+	for _, st := range s.stakes {
+		if !st.IsCloses {
+			staked += st.TrxAmount
+		}
+	}
+	if amount > staked {
+		return nil, errValidation("trx_amount", "The trx_amount exceeds the staked amount.")
+	}
+
+	now := s.timestamp()
+	remaining := amount
+	for i := range s.stakes {
+		st := &s.stakes[i]
+		if st.IsCloses || remaining <= 0 {
+			continue
+		}
+		take := min(st.TrxAmount, remaining)
+		remaining -= take
+		if take == st.TrxAmount {
+			st.IsCloses = true
+			st.ClosesAt = &now
+		} else {
+			st.TrxAmount -= take
+		}
+	}
+	s.account.Balance += amount
+	s.record(amount, trenergy.TxTypeUnstake, 0)
+
+	return map[string]trenergy.Timestamp{
+		"unstake_date": s.at(14 * 24 * time.Hour),
+	}, nil
+}
+
+func (s *Server) syncStakes(r *http.Request) (any, error) {
+	return struct{}{}, nil
+}
+
+func (s *Server) stakeProfitability(r *http.Request) (any, error) {
+	period, _ := strconv.Atoi(r.URL.Query().Get("period"))
+	switch period {
+	case 7, 30, 365:
+	default:
+		return nil, errValidation("period", "The selected period is invalid.")
+	}
+	items := make([]trenergy.StakeProfitabilityItem, period)
+	start := s.now().AddDate(0, 0, -period+1)
+	for i := range items {
+		day := start.AddDate(0, 0, i)
+		items[i].Date = trenergy.Timestamp{Time: time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)}
+	}
+	return items, nil
+}
+
+// Wallets
+
+func (s *Server) listWallets(r *http.Request) (any, error) {
+	wallets := s.wallets
+	if wallets == nil {
+		wallets = []trenergy.Wallet{}
+	}
+	return wallets, nil
+}
+
+func (s *Server) addWallet(r *http.Request) (any, error) {
+	address := r.FormValue("address")
+	if !validAddress(address) {
+		return nil, errValidation("address", "The address is invalid.")
+	}
+	now := s.timestamp()
+	w := trenergy.Wallet{ID: s.newID(), Address: address, CreatedAt: now, UpdatedAt: now}
+	s.wallets = append(s.wallets, w)
+	return w, nil
+}
+
+func (s *Server) deleteWallet(r *http.Request) (any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	idx := slices.IndexFunc(s.wallets, func(w trenergy.Wallet) bool { return w.ID == id })
+	if idx < 0 {
+		return nil, errNotFound()
+	}
+	s.wallets = slices.Delete(s.wallets, idx, idx+1)
+	return struct{}{}, nil
+}
+
+// Withdrawals
+
+func (s *Server) listWithdrawals(r *http.Request) (any, error) {
+	return paginate(r, s.withdrawals), nil
+}
+
+func (s *Server) createWithdrawal(r *http.Request) (any, error) {
+	amount, err := formTRX(r, "trx_amount")
+	if err != nil {
+		return nil, err
+	}
+	address := r.FormValue("address")
+	if address != "" && !validAddress(address) {
+		return nil, errValidation("address", "The address is invalid.")
+	}
+	id := s.newID()
+	if err := s.debit(amount, trenergy.TxTypeWithdrawal, id); err != nil {
+		return nil, err
+	}
+	now := s.timestamp()
+	s.withdrawals = append(s.withdrawals, trenergy.Withdrawal{
+		ID:        id,
+		TrxAmount: amount,
+		Status:    "pending",
+		Address:   address,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	return struct{}{}, nil
+}
+
+// Transactions
+
+func (s *Server) listTransactions(r *http.Request) (any, error) {
+	q := r.URL.Query()
+	items := slices.Clone(s.transactions)
+
+	if types := q["types[]"]; len(types) > 0 {
+		items = slices.DeleteFunc(items, func(tx trenergy.InternalTransaction) bool {
+			return !slices.Contains(types, strconv.Itoa(int(tx.Type)))
+		})
+	}
+	if date := q.Get("date"); date != "" {
+		items = slices.DeleteFunc(items, func(tx trenergy.InternalTransaction) bool {
+			return tx.CreatedAt.Format(time.DateOnly) != date
+		})
+	}
+
+	desc := q.Get("sort_direction") == "desc"
+	slices.SortStableFunc(items, func(a, b trenergy.InternalTransaction) int {
+		var order int
+		if q.Get("sort_by") == "amount" {
+			order = cmp.Compare(a.Amount, b.Amount)
+		} else {
+			order = a.CreatedAt.Compare(b.CreatedAt.Time)
+		}
+		if desc {
+			return -order
+		}
+		return order
+	})
+	return paginate(r, items), nil
+}
+
+// Structure
+
+func (s *Server) listPartners(r *http.Request) (any, error) {
+	partners := s.partners
+	if partners == nil {
+		partners = []trenergy.Partner{}
+	}
+	return partners, nil
+}
@@ -0,0 +1,394 @@
+// Package trenergytest provides an in-memory fake of the Tr.Energy API for
+// tests. It serves the endpoints covered by the trenergy SDK from an
+// httptest.Server and keeps a simple balance model, so that code using the
+// SDK can be exercised offline:
+//
+//	fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+//	defer fake.Close()
+//	client := trenergy.NewClient(fake.APIKey, trenergy.WithBaseURL(fake.URL))
+package trenergytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cyvadra/trenergy"
+)
+
+// DefaultAPIKey is the API key accepted by a Server unless WithAPIKey is used.
+const DefaultAPIKey = "trenergytest-key"
+
+// Server is a fake Tr.Energy API server.
+type Server struct {
+	// URL is the base URL of the server, suitable for trenergy.WithBaseURL.
+	URL string
+	// APIKey is the bearer token the server accepts.
+	APIKey string
+
+	srv *httptest.Server
+	now func() time.Time
+
+	mu                   sync.Mutex
+	failures             []*Failure
+	account              trenergy.AccountInfo
+	pricesEnergy         map[string]float64
+	pricesBandwidth      map[string]float64
+	addressActivationFee trenergy.TRX
+	consumers            []*trenergy.Consumer
+	payments             map[int][]trenergy.ConsumerPayment
+	activated            map[string]bool
+	amlChecks            []trenergy.AMLCheck
+	stakes               []trenergy.Stake
+	wallets              []trenergy.Wallet
+	withdrawals          []trenergy.Withdrawal
+	transactions         []trenergy.InternalTransaction
+	partners             []trenergy.Partner
+	massBatchLimit       int
+	nextID               int
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithAPIKey sets the API key the server accepts.
+func WithAPIKey(key string) Option {
+	return func(s *Server) {
+		s.APIKey = key
+	}
+}
+
+// WithBalance sets the initial account balance.
+func WithBalance(trx trenergy.TRX) Option {
+	return func(s *Server) {
+		s.account.Balance = trx
+	}
+}
+
+// WithClock overrides the clock used for timestamps and order expiry.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithEnergyPrices sets the energy price in SUN per unit for each payment
+// period, keyed by the period in minutes.
+func WithEnergyPrices(prices map[int]float64) Option {
+	return func(s *Server) {
+		s.pricesEnergy = priceTable(prices)
+	}
+}
+
+// WithBandwidthPrices sets the bandwidth price in SUN per unit for each
+// payment period, keyed by the period in minutes.
+func WithBandwidthPrices(prices map[int]float64) Option {
+	return func(s *Server) {
+		s.pricesBandwidth = priceTable(prices)
+	}
+}
+
+// WithPartners sets the partner structure returned by /api/structure/partners.
+func WithPartners(partners []trenergy.Partner) Option {
+	return func(s *Server) {
+		s.partners = partners
+	}
+}
+
+// WithMassBatchLimit sets how many consumers a mass request may name. By
+// default there is no limit.
+func WithMassBatchLimit(n int) Option {
+	return func(s *Server) {
+		s.massBatchLimit = n
+	}
+}
+
+func priceTable(prices map[int]float64) map[string]float64 {
+	table := make(map[string]float64, len(prices))
+	for period, price := range prices {
+		table[strconv.Itoa(period)] = price
+	}
+	return table
+}
+
+// NewServer starts a fake server. Callers must Close it when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		APIKey: DefaultAPIKey,
+		now:    time.Now,
+		account: trenergy.AccountInfo{
+			Name:  "trenergytest",
+			Email: "test@example.com",
+			Lang:  "en",
+		},
+		pricesEnergy:         map[string]float64{"15": 65, "60": 75, "1440": 90},
+		pricesBandwidth:      map[string]float64{"15": 650, "60": 750, "1440": 900},
+		addressActivationFee: 3 * trenergy.OneTRX / 2,
+		payments:             make(map[int][]trenergy.ConsumerPayment),
+		activated:            make(map[string]bool),
+		nextID:               1,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.srv = httptest.NewServer(s.routes())
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// NewClient returns a client pointed at the server and authenticated with its API key.
+func (s *Server) NewClient(opts ...trenergy.Option) *trenergy.Client {
+	opts = append([]trenergy.Option{trenergy.WithBaseURL(s.URL)}, opts...)
+	return trenergy.NewClient(s.APIKey, opts...)
+}
+
+// Balance returns the current account balance.
+func (s *Server) Balance() trenergy.TRX {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.account.Balance
+}
+
+// SetBalance sets the account balance.
+func (s *Server) SetBalance(trx trenergy.TRX) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.account.Balance = trx
+}
+
+// Consumers returns a snapshot of all consumers.
+func (s *Server) Consumers() []trenergy.Consumer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]trenergy.Consumer, len(s.consumers))
+	for i, c := range s.consumers {
+		out[i] = *c
+	}
+	return out
+}
+
+// Failure describes an injected failure.
+type Failure struct {
+	// Method matches the request method. Empty matches any method.
+	Method string
+	// Path matches the request URL path exactly. Empty matches any path.
+	Path string
+	// Status is the HTTP status to answer with. Zero means 500.
+	Status int
+	// Body is the raw response body. If empty, a JSON body with a generic
+	// message is sent.
+	Body string
+	// Header is added to the response, e.g. Retry-After.
+	Header http.Header
+	// Times is how many matching requests fail. Zero means once.
+	Times int
+}
+
+// InjectFailure makes the next matching requests fail as described by f.
+// Failures are consumed in the order they were injected.
+func (s *Server) InjectFailure(f Failure) {
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	if f.Status == 0 {
+		f.Status = http.StatusInternalServerError
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &f)
+}
+
+// takeFailure returns and consumes the first failure matching r.
+func (s *Server) takeFailure(r *http.Request) *Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.failures {
+		if (f.Method == "" || f.Method == r.Method) && (f.Path == "" || f.Path == r.URL.Path) {
+			f.Times--
+			if f.Times == 0 {
+				s.failures = slices.Delete(s.failures, i, i+1)
+			}
+			return f
+		}
+	}
+	return nil
+}
+
+// apiError is a handler result that is rendered as an error response.
+type apiError struct {
+	status  int
+	message string
+	errors  map[string][]string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func errInsufficientFunds() *apiError {
+	return &apiError{status: http.StatusUnprocessableEntity, message: "Not enough funds"}
+}
+
+func errValidation(field, msg string) *apiError {
+	return &apiError{
+		status:  http.StatusUnprocessableEntity,
+		message: msg,
+		errors:  map[string][]string{field: {msg}},
+	}
+}
+
+func errNotFound() *apiError {
+	return &apiError{status: http.StatusNotFound, message: "Not found"}
+}
+
+// handlerFunc is an endpoint implementation. It runs with s.mu held and
+// returns the value to send as "data", or an *apiError.
+type handlerFunc func(r *http.Request) (any, error)
+
+// handle wraps h with authentication, failure injection and response encoding.
+func (s *Server) handle(h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f := s.takeFailure(r); f != nil {
+			for k, v := range f.Header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(f.Status)
+			if f.Body != "" {
+				w.Write([]byte(f.Body))
+			} else {
+				fmt.Fprintf(w, `{"status":false,"message":%q}`, http.StatusText(f.Status))
+			}
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer "+s.APIKey {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"message": "Unauthenticated."})
+			return
+		}
+
+		if err := parseForm(r); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"status": false, "message": err.Error()})
+			return
+		}
+
+		// Handlers return values that alias the server state, so the
+		// response is encoded before the lock is released.
+		s.mu.Lock()
+		status, body := s.respond(h, r)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+	})
+}
+
+// respond runs h and encodes its response. It must be called with s.mu held.
+func (s *Server) respond(h handlerFunc, r *http.Request) (int, []byte) {
+	status, v := http.StatusOK, any(nil)
+	data, err := h(r)
+	if err != nil {
+		apiErr, ok := err.(*apiError)
+		if !ok {
+			apiErr = &apiError{status: http.StatusInternalServerError, message: err.Error()}
+		}
+		body := map[string]any{"status": false, "message": apiErr.message}
+		if len(apiErr.errors) > 0 {
+			body["errors"] = apiErr.errors
+		}
+		status, v = apiErr.status, body
+	} else if p, ok := data.(page); ok {
+		v = p
+	} else {
+		v = map[string]any{"status": true, "data": data}
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Appendf(nil, `{"status":false,"message":%q}`, err.Error())
+	}
+	return status, append(body, '\n')
+}
+
+// parseForm parses urlencoded and multipart bodies into r.Form.
+func parseForm(r *http.Request) error {
+	err := r.ParseMultipartForm(1 << 20)
+	if err == http.ErrNotMultipart {
+		return nil
+	}
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// page is a paginated list response.
+type page struct {
+	Status bool           `json:"status"`
+	Data   any            `json:"data"`
+	Links  trenergy.Links `json:"links"`
+	Meta   trenergy.Meta  `json:"meta"`
+}
+
+const defaultPerPage = 15
+
+// paginate slices items according to the page and per_page query parameters.
+func paginate[T any](r *http.Request, items []T) page {
+	current, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if current < 1 {
+		current = 1
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+	lastPage := max(1, (len(items)+perPage-1)/perPage)
+
+	from := min((current-1)*perPage, len(items))
+	to := min(from+perPage, len(items))
+	data := items[from:to]
+	if data == nil {
+		data = []T{}
+	}
+
+	link := func(n int) string {
+		return fmt.Sprintf("%s?page=%d", r.URL.Path, n)
+	}
+	p := page{
+		Status: true,
+		Data:   data,
+		Links:  trenergy.Links{First: link(1), Last: link(lastPage)},
+		Meta: trenergy.Meta{
+			CurrentPage: current,
+			LastPage:    lastPage,
+			Path:        r.URL.Path,
+			PerPage:     perPage,
+			Total:       len(items),
+		},
+	}
+	if current > 1 {
+		p.Links.Prev = link(current - 1)
+	}
+	if current < lastPage {
+		p.Links.Next = link(current + 1)
+	}
+	if to > from {
+		p.Meta.From = from + 1
+		p.Meta.To = to
+	}
+	return p
+}
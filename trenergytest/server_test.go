@@ -0,0 +1,170 @@
+package trenergytest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/trenergytest"
+)
+
+const testAddress = "TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF"
+
+func TestBootstrapOrderChargesBalance(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(10 * trenergy.OneTRX))
+	defer fake.Close()
+	client := fake.NewClient()
+	ctx := context.Background()
+
+	resp, err := client.CreateBootstrapOrder(ctx, trenergy.ConsumerParams{
+		Address:        testAddress,
+		PaymentPeriod:  60,
+		ResourceAmount: 65000,
+		Resource:       1,
+	})
+	if err != nil {
+		t.Fatalf("CreateBootstrapOrder failed: %v", err)
+	}
+	if resp.Data.ID == 0 || resp.Data.Order == nil || resp.Data.Order.CompletionPercentage != 100 {
+		t.Errorf("unexpected consumer %+v", resp.Data)
+	}
+	// 65000 energy at 75 SUN each.
+	want := 10*trenergy.OneTRX - 65000*75*trenergy.Sun
+	if got := fake.Balance(); got != want {
+		t.Errorf("expected balance %v, got %v", want, got)
+	}
+
+	account, err := client.GetAccountInfo(ctx)
+	if err != nil {
+		t.Fatalf("GetAccountInfo failed: %v", err)
+	}
+	if account.Data.Balance != want {
+		t.Errorf("expected account balance %v, got %v", want, account.Data.Balance)
+	}
+
+	_, err = client.CreateBootstrapOrder(ctx, trenergy.ConsumerParams{
+		Address:        testAddress,
+		PaymentPeriod:  60,
+		ResourceAmount: 131000,
+		Resource:       1,
+	})
+	if !errors.Is(err, trenergy.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	_, err = client.CreateBootstrapOrder(ctx, trenergy.ConsumerParams{
+		Address:        testAddress,
+		PaymentPeriod:  7,
+		ResourceAmount: 1000,
+		Resource:       1,
+	})
+	if !errors.Is(err, trenergy.ErrInvalidPaymentPeriod) {
+		t.Errorf("expected ErrInvalidPaymentPeriod, got %v", err)
+	}
+}
+
+func TestConsumerLifecycle(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(1000 * trenergy.OneTRX))
+	defer fake.Close()
+	client := fake.NewClient()
+	ctx := context.Background()
+
+	for range 20 {
+		if _, err := client.CreateBootstrapOrder(ctx, trenergy.ConsumerParams{
+			Address: testAddress, PaymentPeriod: 15, ResourceAmount: 1000, Resource: 1,
+		}); err != nil {
+			t.Fatalf("CreateBootstrapOrder failed: %v", err)
+		}
+	}
+
+	consumers, err := trenergy.CollectAll(client.AllConsumers(ctx), 0)
+	if err != nil {
+		t.Fatalf("AllConsumers failed: %v", err)
+	}
+	if len(consumers) != 20 {
+		t.Fatalf("expected 20 consumers, got %d", len(consumers))
+	}
+
+	id := consumers[0].ID
+	if _, err := client.DeactivateConsumer(ctx, id); err != nil {
+		t.Fatalf("DeactivateConsumer failed: %v", err)
+	}
+	got, err := client.GetConsumer(ctx, id)
+	if err != nil {
+		t.Fatalf("GetConsumer failed: %v", err)
+	}
+	if got.Data.IsActive {
+		t.Error("expected consumer to be inactive")
+	}
+
+	if _, err := client.DeleteConsumer(ctx, id); err != nil {
+		t.Fatalf("DeleteConsumer failed: %v", err)
+	}
+	if _, err := client.GetConsumer(ctx, id); !errors.Is(err, trenergy.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUpdateConsumer(t *testing.T) {
+	fake := trenergytest.NewServer(trenergytest.WithBalance(100 * trenergy.OneTRX))
+	defer fake.Close()
+	client := fake.NewClient()
+	ctx := context.Background()
+
+	created, err := client.CreateBootstrapOrder(ctx, trenergy.ConsumerParams{
+		Address: testAddress, PaymentPeriod: 15, ResourceAmount: 1000, Resource: 1,
+		Name: "wallet", WebhookURL: "https://example.com/hook",
+	})
+	if err != nil {
+		t.Fatalf("CreateBootstrapOrder failed: %v", err)
+	}
+	id := created.Data.ID
+
+	resp, err := client.UpdateConsumer(ctx, id, trenergy.UpdateConsumerParams{
+		ResourceAmount: trenergy.Ptr[int64](65000),
+		PaymentPeriod:  trenergy.Ptr(trenergy.PaymentPeriod1D),
+		AutoRenewal:    trenergy.Ptr(true),
+		WebhookURL:     trenergy.Ptr(""),
+	})
+	if err != nil {
+		t.Fatalf("UpdateConsumer failed: %v", err)
+	}
+	c := resp.Data
+	if c.ResourceAmount.Value != 65000 || c.PaymentPeriod != trenergy.PaymentPeriod1D || !c.AutoRenewal {
+		t.Errorf("fields not updated: %+v", c)
+	}
+	if c.WebhookURL != "" {
+		t.Errorf("expected webhook to be cleared, got %q", c.WebhookURL)
+	}
+	if c.Name != "wallet" {
+		t.Errorf("expected name to be kept, got %q", c.Name)
+	}
+
+	_, err = client.UpdateConsumer(ctx, id, trenergy.UpdateConsumerParams{PaymentPeriod: trenergy.Ptr(trenergy.PaymentPeriod(7))})
+	if !errors.Is(err, trenergy.ErrInvalidPaymentPeriod) {
+		t.Errorf("expected ErrInvalidPaymentPeriod, got %v", err)
+	}
+}
+
+func TestInjectedFailureAndAuth(t *testing.T) {
+	fake := trenergytest.NewServer()
+	defer fake.Close()
+	ctx := context.Background()
+
+	fake.InjectFailure(trenergytest.Failure{
+		Path:   "/api/account",
+		Status: http.StatusServiceUnavailable,
+		Header: http.Header{"Retry-After": {"0"}},
+	})
+	client := fake.NewClient(trenergy.WithRetryPolicy(trenergy.RetryPolicy{MaxAttempts: 2}))
+	if _, err := client.GetAccountInfo(ctx); err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+
+	unauthorized := trenergy.NewClient("wrong", trenergy.WithBaseURL(fake.URL))
+	if _, err := unauthorized.GetAccountInfo(ctx); !errors.Is(err, trenergy.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
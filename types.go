@@ -2,10 +2,20 @@ package trenergy
 
 // APIResponse represents a generic API response wrapper.
 type APIResponse[T any] struct {
-	Status bool   `json:"status"`
-	Data   T      `json:"data"`
-	Links  *Links `json:"links,omitempty"`
-	Meta   *Meta  `json:"meta,omitempty"`
+	Status  bool   `json:"status"`
+	Message string `json:"message,omitempty"`
+	Data    T      `json:"data"`
+	Links   *Links `json:"links,omitempty"`
+	Meta    *Meta  `json:"meta,omitempty"`
+}
+
+// statusReporter is implemented by response wrappers carrying a status flag.
+type statusReporter interface {
+	apiStatus() bool
+}
+
+func (r *APIResponse[T]) apiStatus() bool {
+	return r.Status
 }
 
 // Links represents pagination links.
@@ -34,6 +44,3 @@ type MetaLink struct {
 	Label  string  `json:"label"`
 	Active bool    `json:"active"`
 }
-
-// ErrorResponse represents an error response from the API (if structured differently, though usually errors are just non-200 with maybe a message).
-// Based on samples, success is status: true. We'll need to handle status: false or HTTP errors.
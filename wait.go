@@ -0,0 +1,203 @@
+package trenergy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrOrderFailed is returned by WaitForOrder when the order fails; see
+	// WithStatusFailures.
+	ErrOrderFailed = errors.New("trenergy: order failed")
+	// ErrOrderExpired is returned by WaitForOrder when the order expires
+	// before it is fully delegated; see WithStatusFailures.
+	ErrOrderExpired = errors.New("trenergy: order expired")
+	// ErrWaitTimeout is returned by WaitForOrder when the timeout set with
+	// WithWaitTimeout elapses.
+	ErrWaitTimeout = errors.New("trenergy: timed out waiting for order")
+)
+
+// WaitError is returned by WaitForOrder. It wraps ErrOrderFailed,
+// ErrOrderExpired, ErrWaitTimeout, the context's error or the error of the
+// last request.
+type WaitError struct {
+	ConsumerID int
+	// Consumer is the last state seen, or nil.
+	Consumer *Consumer
+	Err      error
+}
+
+func (e *WaitError) Error() string {
+	return fmt.Sprintf("trenergy: waiting for consumer %d: %v", e.ConsumerID, e.Err)
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// OrderNotifier pushes consumer updates, typically received by a webhook
+// listener. webhook.Handler implements it.
+type OrderNotifier interface {
+	// WatchConsumer returns a channel receiving updates of the consumer and
+	// a function that stops the watch.
+	WatchConsumer(id int) (<-chan *Consumer, func())
+}
+
+// WithOrderNotifier makes WaitForOrder wait for pushed updates instead of
+// polling GetConsumer.
+func WithOrderNotifier(n OrderNotifier) Option {
+	return func(c *Client) {
+		c.notifier = n
+	}
+}
+
+// WaitOption configures WaitForOrder.
+type WaitOption func(*waitConfig)
+
+type waitConfig struct {
+	minInterval time.Duration
+	maxInterval time.Duration
+	timeout     time.Duration
+	progress    func(*Consumer)
+	failStatus  bool
+}
+
+// WithPollInterval sets the delay between polls. It starts at min and
+// doubles up to max. The defaults are 1s and 15s. With an OrderNotifier,
+// max is also the interval of the safety-net poll.
+func WithPollInterval(min, max time.Duration) WaitOption {
+	return func(w *waitConfig) {
+		w.minInterval = min
+		w.maxInterval = max
+	}
+}
+
+// WithWaitTimeout bounds the wait, after which ErrWaitTimeout is returned.
+func WithWaitTimeout(d time.Duration) WaitOption {
+	return func(w *waitConfig) {
+		w.timeout = d
+	}
+}
+
+// WithStatusFailures ends the wait with ErrOrderFailed or ErrOrderExpired
+// when the order reports OrderStatusFailed or OrderStatusExpired. Those
+// status codes are unconfirmed, so by default WaitForOrder relies only on the
+// completion percentage and waits until it reaches 100, the timeout elapses
+// or the context is done.
+func WithStatusFailures() WaitOption {
+	return func(w *waitConfig) {
+		w.failStatus = true
+	}
+}
+
+// WithOrderProgress calls fn with every state of the consumer seen while waiting.
+func WithOrderProgress(fn func(*Consumer)) WaitOption {
+	return func(w *waitConfig) {
+		w.progress = fn
+	}
+}
+
+// WaitForOrder blocks until the order of the consumer is fully delegated,
+// i.e. its completion percentage reaches 100, and returns the consumer.
+//
+// It polls GetConsumer with exponential backoff, or waits for updates from
+// the client's OrderNotifier if one is configured, polling only every max
+// interval in case a notification is lost. Transient request failures are
+// tolerated. Errors are returned as a *WaitError.
+func (c *Client) WaitForOrder(ctx context.Context, consumerID int, opts ...WaitOption) (*Consumer, error) {
+	cfg := waitConfig{
+		minInterval: time.Second,
+		maxInterval: 15 * time.Second,
+		progress:    func(*Consumer) {},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.maxInterval = max(cfg.maxInterval, cfg.minInterval)
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, cfg.timeout, ErrWaitTimeout)
+		defer cancel()
+	}
+
+	var updates <-chan *Consumer
+	interval := cfg.minInterval
+	if c.notifier != nil {
+		var stop func()
+		updates, stop = c.notifier.WatchConsumer(consumerID)
+		defer stop()
+		interval = cfg.maxInterval
+	}
+
+	var last *Consumer
+	fail := func(err error) (*Consumer, error) {
+		return last, &WaitError{ConsumerID: consumerID, Consumer: last, Err: err}
+	}
+	// check reports whether waiting is over, with the error if it failed.
+	check := func(consumer *Consumer) (bool, error) {
+		last = consumer
+		cfg.progress(consumer)
+		o := consumer.Order
+		switch {
+		case o == nil:
+			return false, nil
+		case o.CompletionPercentage >= 100:
+			return true, nil
+		case cfg.failStatus && o.Status == OrderStatusFailed:
+			return true, ErrOrderFailed
+		case cfg.failStatus && o.Status == OrderStatusExpired:
+			return true, ErrOrderExpired
+		}
+		return false, nil
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fail(context.Cause(ctx))
+		case consumer := <-updates:
+			if consumer == nil {
+				continue
+			}
+			if done, err := check(consumer); done {
+				if err != nil {
+					return fail(err)
+				}
+				return consumer, nil
+			}
+		case <-timer.C:
+			resp, err := c.GetConsumer(ctx, consumerID)
+			switch {
+			case err == nil && resp.Data != nil:
+				if done, err := check(resp.Data); done {
+					if err != nil {
+						return fail(err)
+					}
+					return resp.Data, nil
+				}
+			case ctx.Err() != nil:
+				return fail(context.Cause(ctx))
+			case err != nil && !transient(err):
+				return fail(err)
+			}
+			timer.Reset(interval)
+			interval = min(interval*2, cfg.maxInterval)
+		}
+	}
+}
+
+// transient reports whether a failed poll is worth retrying.
+func transient(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// Network errors.
+		return true
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+}
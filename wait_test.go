@@ -0,0 +1,135 @@
+package trenergy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cyvadra/trenergy"
+)
+
+// newOrderServer serves consumer 1 with the order states returned by state,
+// called with the number of the poll starting at 1.
+func newOrderServer(t *testing.T, state func(n int) (status, percent int)) *httptest.Server {
+	t.Helper()
+	var polls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/consumers/1" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not found"}`)
+			return
+		}
+		status, percent := state(int(polls.Add(1)))
+		if status < 0 {
+			w.WriteHeader(-status)
+			fmt.Fprint(w, `{"message":"error"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"status":true,"data":{"id":1,"order":{"status":%d,"completion_percentage":%d}}}`, status, percent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWaitForOrder(t *testing.T) {
+	srv := newOrderServer(t, func(n int) (int, int) {
+		switch n {
+		case 1:
+			return 0, 0
+		case 2:
+			return -http.StatusServiceUnavailable, 0
+		case 3:
+			return 1, 50
+		}
+		return 2, 100
+	})
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL))
+
+	var seen []int
+	c, err := client.WaitForOrder(context.Background(), 1,
+		trenergy.WithPollInterval(time.Millisecond, 5*time.Millisecond),
+		trenergy.WithOrderProgress(func(c *trenergy.Consumer) {
+			seen = append(seen, c.Order.CompletionPercentage)
+		}))
+	if err != nil {
+		t.Fatalf("WaitForOrder failed: %v", err)
+	}
+	if c.Order.CompletionPercentage != 100 {
+		t.Errorf("expected a completed order, got %+v", c.Order)
+	}
+	if fmt.Sprint(seen) != "[0 50 100]" {
+		t.Errorf("unexpected progress %v", seen)
+	}
+}
+
+func TestWaitForOrderErrors(t *testing.T) {
+	fast := trenergy.WithPollInterval(time.Millisecond, time.Millisecond)
+
+	failed := newOrderServer(t, func(int) (int, int) { return int(trenergy.OrderStatusFailed), 30 })
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(failed.URL))
+	// The status code alone does not end the wait by default.
+	_, err := client.WaitForOrder(context.Background(), 1, fast, trenergy.WithWaitTimeout(20*time.Millisecond))
+	if !errors.Is(err, trenergy.ErrWaitTimeout) {
+		t.Errorf("expected ErrWaitTimeout, got %v", err)
+	}
+	_, err = client.WaitForOrder(context.Background(), 1, fast, trenergy.WithStatusFailures())
+	var waitErr *trenergy.WaitError
+	if !errors.Is(err, trenergy.ErrOrderFailed) || !errors.As(err, &waitErr) || waitErr.Consumer == nil {
+		t.Errorf("expected ErrOrderFailed with the last state, got %v", err)
+	}
+
+	pending := newOrderServer(t, func(int) (int, int) { return 0, 0 })
+	client = trenergy.NewClient("key", trenergy.WithBaseURL(pending.URL))
+	_, err = client.WaitForOrder(context.Background(), 1, fast, trenergy.WithWaitTimeout(20*time.Millisecond))
+	if !errors.Is(err, trenergy.ErrWaitTimeout) {
+		t.Errorf("expected ErrWaitTimeout, got %v", err)
+	}
+
+	_, err = client.WaitForOrder(context.Background(), 2, fast)
+	if !errors.Is(err, trenergy.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// chanNotifier is an OrderNotifier fed by the test.
+type chanNotifier struct {
+	ch      chan *trenergy.Consumer
+	stopped atomic.Bool
+}
+
+func (n *chanNotifier) WatchConsumer(int) (<-chan *trenergy.Consumer, func()) {
+	return n.ch, func() { n.stopped.Store(true) }
+}
+
+func TestWaitForOrderNotifier(t *testing.T) {
+	var polls atomic.Int32
+	srv := newOrderServer(t, func(n int) (int, int) {
+		polls.Store(int32(n))
+		return 0, 0
+	})
+	notifier := &chanNotifier{ch: make(chan *trenergy.Consumer, 1)}
+	client := trenergy.NewClient("key", trenergy.WithBaseURL(srv.URL), trenergy.WithOrderNotifier(notifier))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		notifier.ch <- &trenergy.Consumer{ID: 1, Order: &trenergy.Order{Status: trenergy.OrderStatusCompleted, CompletionPercentage: 100}}
+	}()
+	c, err := client.WaitForOrder(context.Background(), 1, trenergy.WithPollInterval(time.Millisecond, time.Hour))
+	if err != nil {
+		t.Fatalf("WaitForOrder failed: %v", err)
+	}
+	if c.Order.CompletionPercentage != 100 {
+		t.Errorf("unexpected order %+v", c.Order)
+	}
+	if n := polls.Load(); n != 1 {
+		t.Errorf("expected only the initial poll, got %d", n)
+	}
+	if !notifier.stopped.Load() {
+		t.Error("expected the watch to be stopped")
+	}
+}
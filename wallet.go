@@ -7,10 +7,10 @@ import (
 
 // Wallet represents a user's wallet.
 type Wallet struct {
-	ID        int    `json:"id"`
-	Address   string `json:"address"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID        int       `json:"id"`
+	Address   string    `json:"address"`
+	CreatedAt Timestamp `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
 }
 
 // ListWallets retrieves a list of wallets.
@@ -25,12 +25,16 @@ func (c *Client) ListWallets(ctx context.Context) (*APIResponse[[]Wallet], error
 
 // AddWallet adds a new wallet.
 func (c *Client) AddWallet(ctx context.Context, address string) (*APIResponse[*Wallet], error) {
-	data := make(map[string]string)
-	data["address"] = address
+	if err := ValidateAddress(address); err != nil {
+		return nil, err
+	}
+	form := struct {
+		Address string `form:"address"`
+	}{Address: address}
 
 	// Sample 2691 uses FormData
 	var resp APIResponse[*Wallet]
-	err := c.postMultipart(ctx, "/api/wallets", data, &resp)
+	err := c.sendForm(ctx, "POST", "/api/wallets", bodyMultipart, form, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,481 @@
+// Package webhook receives the callbacks Tr.Energy sends to a consumer's
+// WebhookURL.
+//
+// A Handler is an http.Handler that authenticates the request, decodes the
+// payload into a typed Event, drops redeliveries it has already processed and
+// dispatches the event to the registered callbacks:
+//
+//	h := webhook.NewHandler(webhook.WithToken(os.Getenv("WEBHOOK_TOKEN")))
+//	h.On(webhook.EventOrderCompleted, func(ctx context.Context, e *webhook.Event) error {
+//		log.Printf("consumer %d is ready", e.Consumer.ID)
+//		return nil
+//	})
+//	http.Handle("/trenergy/webhook", h)
+//
+// The API does not document a signature scheme, so authenticity is checked
+// with a secret token embedded in the WebhookURL (WithToken), an HMAC of the
+// body (WithHMAC) if a proxy in front of the service adds one, or a custom
+// Verifier. NewHandler requires at least one of them unless
+// WithoutVerification is given.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyvadra/trenergy"
+)
+
+// EventType identifies what happened to a consumer order.
+type EventType string
+
+const (
+	EventOrderCreated   EventType = "order.created"
+	EventOrderDelegated EventType = "order.delegated"
+	EventOrderCompleted EventType = "order.completed"
+	EventOrderExpired   EventType = "order.expired"
+	EventRenewalFailed  EventType = "order.renewal_failed"
+)
+
+// Event is a decoded webhook delivery.
+type Event struct {
+	// ID identifies the delivery for deduplication. It is taken from the
+	// payload when present and derived from its content otherwise.
+	ID       string
+	Type     EventType
+	Consumer *trenergy.Consumer
+	// Raw is the request body.
+	Raw        json.RawMessage
+	ReceivedAt time.Time
+}
+
+// HandlerFunc processes an event. Returning an error answers the delivery
+// with 500 so that it is retried, and the event is not marked as seen.
+// While an event is being processed, redeliveries of it are answered with
+// 409 so that the sender retries them later. A handler that panics, or that
+// is still running after five minutes, no longer blocks redeliveries.
+type HandlerFunc func(ctx context.Context, e *Event) error
+
+// Verifier authenticates a delivery given the request and its body.
+type Verifier func(r *http.Request, body []byte) error
+
+// ErrUnauthenticated is returned by verifiers when a delivery is rejected.
+var ErrUnauthenticated = errors.New("webhook: unauthenticated delivery")
+
+// Handler is an http.Handler for Tr.Energy webhook deliveries.
+type Handler struct {
+	verifiers   []Verifier
+	insecure    bool
+	dedupWindow time.Duration
+	maxBodySize int64
+	now         func() time.Time
+	onError     func(error)
+
+	mu       sync.RWMutex
+	handlers map[EventType][]HandlerFunc
+	any      []HandlerFunc
+	subs     map[int]map[*subscription]struct{}
+
+	seenMu    sync.Mutex
+	seen      map[string]seenEvent
+	nextSweep time.Time
+}
+
+// seenEvent is the deduplication state of an event ID.
+type seenEvent struct {
+	at   time.Time
+	done bool
+}
+
+// sweepInterval is how often expired event IDs are removed.
+const sweepInterval = time.Minute
+
+// processingTimeout is how long an event stays in progress. After it, a
+// redelivery is processed again even if the first attempt never finished.
+const processingTimeout = 5 * time.Minute
+
+// expired reports whether the entry no longer blocks a redelivery at now.
+func (e seenEvent) expired(now time.Time, dedupWindow time.Duration) bool {
+	if e.done {
+		return now.Sub(e.at) > dedupWindow
+	}
+	return now.Sub(e.at) > processingTimeout
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithToken requires deliveries to carry token either as the "token" query
+// parameter of the WebhookURL or in the X-Webhook-Token header.
+func WithToken(token string) Option {
+	return WithVerifier(func(r *http.Request, _ []byte) error {
+		got := r.URL.Query().Get("token")
+		if got == "" {
+			got = r.Header.Get("X-Webhook-Token")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return ErrUnauthenticated
+		}
+		return nil
+	})
+}
+
+// WithHMAC requires the named header to hold the hex HMAC-SHA256 of the body
+// keyed with secret. A "sha256=" prefix is accepted.
+func WithHMAC(secret []byte, header string) Option {
+	return WithVerifier(func(r *http.Request, body []byte) error {
+		got, err := hex.DecodeString(strings.TrimPrefix(r.Header.Get(header), "sha256="))
+		if err != nil {
+			return ErrUnauthenticated
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		if !hmac.Equal(got, mac.Sum(nil)) {
+			return ErrUnauthenticated
+		}
+		return nil
+	})
+}
+
+// WithVerifier adds a custom verifier. All verifiers must pass.
+func WithVerifier(v Verifier) Option {
+	return func(h *Handler) {
+		h.verifiers = append(h.verifiers, v)
+	}
+}
+
+// WithoutVerification accepts deliveries without authenticating them. Anyone
+// who can reach the handler can then inject events; use it only behind a
+// proxy that authenticates deliveries, or in tests.
+func WithoutVerification() Option {
+	return func(h *Handler) {
+		h.insecure = true
+	}
+}
+
+// WithDedupWindow sets how long processed event IDs are remembered.
+// The default is 24 hours.
+func WithDedupWindow(d time.Duration) Option {
+	return func(h *Handler) {
+		h.dedupWindow = d
+	}
+}
+
+// WithMaxBodySize limits the accepted body size. The default is 1 MiB.
+func WithMaxBodySize(n int64) Option {
+	return func(h *Handler) {
+		h.maxBodySize = n
+	}
+}
+
+// WithErrorLog receives rejected deliveries and handler errors.
+func WithErrorLog(fn func(error)) Option {
+	return func(h *Handler) {
+		h.onError = fn
+	}
+}
+
+// NewHandler creates a Handler. It panics if no verifier is configured and
+// WithoutVerification is not given.
+func NewHandler(opts ...Option) *Handler {
+	h := &Handler{
+		dedupWindow: 24 * time.Hour,
+		maxBodySize: 1 << 20,
+		now:         time.Now,
+		onError:     func(error) {},
+		handlers:    make(map[EventType][]HandlerFunc),
+		subs:        make(map[int]map[*subscription]struct{}),
+		seen:        make(map[string]seenEvent),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if len(h.verifiers) == 0 && !h.insecure {
+		panic("webhook: NewHandler needs WithToken, WithHMAC, WithVerifier or WithoutVerification")
+	}
+	return h
+}
+
+// On registers fn for events of type t.
+func (h *Handler) On(t EventType, fn HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[t] = append(h.handlers[t], fn)
+}
+
+// OnAny registers fn for every event.
+func (h *Handler) OnAny(fn HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.any = append(h.any, fn)
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBodySize))
+	if err != nil {
+		h.onError(fmt.Errorf("webhook: reading body: %w", err))
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	for _, verify := range h.verifiers {
+		if err := verify(r, body); err != nil {
+			h.onError(err)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	event, err := Decode(body)
+	if err != nil {
+		h.onError(err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	event.ReceivedAt = h.now()
+
+	switch state, seen := h.markSeen(event.ID); {
+	case seen && state.done:
+		w.WriteHeader(http.StatusOK)
+		return
+	case seen:
+		http.Error(w, "delivery in progress", http.StatusConflict)
+		return
+	}
+
+	// Forget the event unless it was processed, so that a handler that
+	// panics does not leave it in progress.
+	done := false
+	defer func() {
+		if !done {
+			h.forget(event.ID)
+		}
+	}()
+	if err := h.dispatch(r.Context(), event); err != nil {
+		h.onError(err)
+		http.Error(w, "handler failed", http.StatusInternalServerError)
+		return
+	}
+	h.markDone(event.ID)
+	done = true
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) dispatch(ctx context.Context, e *Event) error {
+	h.mu.RLock()
+	fns := append(append([]HandlerFunc(nil), h.handlers[e.Type]...), h.any...)
+	h.mu.RUnlock()
+
+	var errs []error
+	for _, fn := range fns {
+		if err := fn(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	h.publish(e)
+	return nil
+}
+
+// markSeen records id as in progress unless it is already known, in which
+// case it returns the existing state and true. Expired IDs are swept at most
+// once per sweepInterval.
+func (h *Handler) markSeen(id string) (seenEvent, bool) {
+	now := h.now()
+	h.seenMu.Lock()
+	defer h.seenMu.Unlock()
+
+	if !now.Before(h.nextSweep) {
+		for k, e := range h.seen {
+			if e.expired(now, h.dedupWindow) {
+				delete(h.seen, k)
+			}
+		}
+		h.nextSweep = now.Add(sweepInterval)
+	}
+	if e, ok := h.seen[id]; ok && !e.expired(now, h.dedupWindow) {
+		return e, true
+	}
+	h.seen[id] = seenEvent{at: now}
+	return seenEvent{}, false
+}
+
+// markDone records that id was processed.
+func (h *Handler) markDone(id string) {
+	now := h.now()
+	h.seenMu.Lock()
+	defer h.seenMu.Unlock()
+	h.seen[id] = seenEvent{at: now, done: true}
+}
+
+func (h *Handler) forget(id string) {
+	h.seenMu.Lock()
+	defer h.seenMu.Unlock()
+	delete(h.seen, id)
+}
+
+// envelope is the wrapped payload shape: an event name and the consumer.
+type envelope struct {
+	ID       json.RawMessage    `json:"id"`
+	Event    EventType          `json:"event"`
+	Type     EventType          `json:"type"`
+	Data     *trenergy.Consumer `json:"data"`
+	Consumer *trenergy.Consumer `json:"consumer"`
+}
+
+// Decode parses a delivery body. It accepts an envelope such as
+// {"event": "order.completed", "id": "...", "data": {consumer}} as well as a
+// bare consumer object, in which case the event type is inferred from the
+// order's completion percentage. The order status codes are unconfirmed, so
+// EventOrderExpired and EventRenewalFailed are only reported when the
+// delivery names them.
+func Decode(body []byte) (*Event, error) {
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, fmt.Errorf("webhook: decoding payload: %w", err)
+	}
+
+	e := &Event{Raw: json.RawMessage(bytes.Clone(body))}
+	e.Consumer = env.Data
+	if e.Consumer == nil {
+		e.Consumer = env.Consumer
+	}
+	e.Type = env.Event
+	if e.Type == "" {
+		e.Type = env.Type
+	}
+
+	if e.Consumer == nil {
+		var c trenergy.Consumer
+		if err := json.Unmarshal(body, &c); err != nil {
+			return nil, fmt.Errorf("webhook: decoding consumer: %w", err)
+		}
+		if c.ID == 0 && c.Address == "" {
+			return nil, errors.New("webhook: payload has no consumer")
+		}
+		e.Consumer = &c
+		// A bare consumer carries its own id, which is not a delivery id.
+		env.ID = nil
+	}
+	if e.Type == "" {
+		e.Type = inferType(e.Consumer)
+	}
+
+	e.ID = eventID(env.ID, e, body)
+	return e, nil
+}
+
+// inferType derives the event type from the completion of the consumer's
+// order.
+func inferType(c *trenergy.Consumer) EventType {
+	switch o := c.Order; {
+	case o == nil:
+		return EventOrderCreated
+	case o.CompletionPercentage >= 100:
+		return EventOrderCompleted
+	case o.CompletionPercentage > 0:
+		return EventOrderDelegated
+	}
+	return EventOrderCreated
+}
+
+func eventID(raw json.RawMessage, e *Event, body []byte) string {
+	if len(raw) > 0 && string(raw) != "null" {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return s
+		}
+		return string(raw)
+	}
+	if c := e.Consumer; c.ID != 0 && c.Order != nil && !c.Order.UpdatedAt.IsZero() {
+		return strconv.Itoa(c.ID) + ":" + string(e.Type) + ":" + c.Order.UpdatedAt.String()
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// subscription delivers events for one consumer.
+type subscription struct {
+	send func(*Event)
+}
+
+// Subscribe returns a channel receiving the events for consumerID that were
+// dispatched successfully, and a function that ends the subscription. Events
+// are dropped if the channel's buffer is full.
+func (h *Handler) Subscribe(consumerID int) (<-chan *Event, func()) {
+	ch := make(chan *Event, 16)
+	cancel := h.subscribe(consumerID, func(e *Event) {
+		select {
+		case ch <- e:
+		default:
+		}
+	})
+	return ch, cancel
+}
+
+// WatchConsumer is like Subscribe but delivers the consumer carried by each
+// event. It implements trenergy.OrderNotifier, so that a Handler can be
+// passed to trenergy.WithOrderNotifier.
+func (h *Handler) WatchConsumer(consumerID int) (<-chan *trenergy.Consumer, func()) {
+	ch := make(chan *trenergy.Consumer, 16)
+	cancel := h.subscribe(consumerID, func(e *Event) {
+		select {
+		case ch <- e.Consumer:
+		default:
+		}
+	})
+	return ch, cancel
+}
+
+func (h *Handler) subscribe(consumerID int, send func(*Event)) func() {
+	sub := &subscription{send: send}
+
+	h.mu.Lock()
+	if h.subs[consumerID] == nil {
+		h.subs[consumerID] = make(map[*subscription]struct{})
+	}
+	h.subs[consumerID][sub] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subs[consumerID], sub)
+			if len(h.subs[consumerID]) == 0 {
+				delete(h.subs, consumerID)
+			}
+		})
+	}
+}
+
+func (h *Handler) publish(e *Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs[e.Consumer.ID] {
+		sub.send(e)
+	}
+}
@@ -0,0 +1,269 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cyvadra/trenergy"
+	"github.com/cyvadra/trenergy/webhook"
+)
+
+const completedConsumer = `{
+	"id": 7,
+	"address": "TG5F5NDGHDyYdgfjV8x96JGh9M593yEJtF",
+	"resource": 1,
+	"order": {
+		"status": 2,
+		"completion_percentage": 100,
+		"updated_at": "2024-05-01T10:00:00.000000Z",
+		"valid_until": "2024-05-01T11:00:00.000000Z"
+	}
+}`
+
+func deliver(h http.Handler, target, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want webhook.EventType
+		id   string
+	}{
+		{"bare completed", completedConsumer, webhook.EventOrderCompleted, "7:order.completed:2024-05-01T10:00:00.000000Z"},
+		{"bare pending", `{"id": 8, "order": {"status": 0}}`, webhook.EventOrderCreated, ""},
+		{"bare delegating", `{"id": 8, "order": {"status": 1, "completion_percentage": 40}}`, webhook.EventOrderDelegated, ""},
+		{"bare completed status", `{"id": 8, "order": {"status": 2, "completion_percentage": 40}}`, webhook.EventOrderDelegated, ""},
+		{"bare expired status", `{"id": 8, "order": {"status": 3}}`, webhook.EventOrderCreated, ""},
+		{"bare failed status", `{"id": 8, "order": {"status": 4, "completion_percentage": 100}}`, webhook.EventOrderCompleted, ""},
+		{"envelope", `{"event": "order.renewal_failed", "id": "evt-1", "data": {"id": 9}}`, webhook.EventRenewalFailed, "evt-1"},
+		{"envelope type key", `{"type": "order.expired", "id": 42, "consumer": {"id": 9}}`, webhook.EventOrderExpired, "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := webhook.Decode([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if e.Type != tt.want {
+				t.Errorf("expected type %q, got %q", tt.want, e.Type)
+			}
+			if tt.id != "" && e.ID != tt.id {
+				t.Errorf("expected id %q, got %q", tt.id, e.ID)
+			}
+			if e.ID == "" || e.Consumer == nil {
+				t.Errorf("incomplete event %+v", e)
+			}
+		})
+	}
+
+	for _, body := range []string{`not json`, `{}`, `[]`} {
+		if _, err := webhook.Decode([]byte(body)); err == nil {
+			t.Errorf("expected error decoding %q", body)
+		}
+	}
+}
+
+func TestHandlerDispatchesAndDeduplicates(t *testing.T) {
+	h := webhook.NewHandler(webhook.WithoutVerification())
+	var completed, all int
+	h.On(webhook.EventOrderCompleted, func(ctx context.Context, e *webhook.Event) error {
+		completed++
+		if e.Consumer.ID != 7 || e.Consumer.Order.Status != trenergy.OrderStatusCompleted {
+			t.Errorf("unexpected consumer %+v", e.Consumer)
+		}
+		return nil
+	})
+	h.On(webhook.EventOrderExpired, func(context.Context, *webhook.Event) error {
+		t.Error("expired handler called")
+		return nil
+	})
+	h.OnAny(func(context.Context, *webhook.Event) error {
+		all++
+		return nil
+	})
+
+	for range 2 {
+		if rec := deliver(h, "/hook", completedConsumer, nil); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+	}
+	if completed != 1 || all != 1 {
+		t.Errorf("expected one dispatch, got completed=%d all=%d", completed, all)
+	}
+
+	if rec := deliver(h, "/hook", "garbage", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/hook", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
+
+func TestHandlerErrorAllowsRedelivery(t *testing.T) {
+	h := webhook.NewHandler(webhook.WithoutVerification())
+	calls := 0
+	h.OnAny(func(context.Context, *webhook.Event) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database down")
+		}
+		return nil
+	})
+
+	if rec := deliver(h, "/hook", completedConsumer, nil); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	if rec := deliver(h, "/hook", completedConsumer, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestVerifiers(t *testing.T) {
+	t.Run("token", func(t *testing.T) {
+		h := webhook.NewHandler(webhook.WithToken("s3cret"))
+		if rec := deliver(h, "/hook", completedConsumer, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 without token, got %d", rec.Code)
+		}
+		if rec := deliver(h, "/hook?token=wrong", completedConsumer, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 with wrong token, got %d", rec.Code)
+		}
+		if rec := deliver(h, "/hook?token=s3cret", completedConsumer, nil); rec.Code != http.StatusOK {
+			t.Errorf("expected 200 with query token, got %d", rec.Code)
+		}
+		header := http.Header{"X-Webhook-Token": {"s3cret"}}
+		if rec := deliver(h, "/hook", `{"id": 1}`, header); rec.Code != http.StatusOK {
+			t.Errorf("expected 200 with header token, got %d", rec.Code)
+		}
+	})
+
+	t.Run("hmac", func(t *testing.T) {
+		secret := []byte("key")
+		h := webhook.NewHandler(webhook.WithHMAC(secret, "X-Signature"))
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(completedConsumer))
+		sig := hex.EncodeToString(mac.Sum(nil))
+
+		if rec := deliver(h, "/hook", completedConsumer, http.Header{"X-Signature": {"sha256=00"}}); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 with bad signature, got %d", rec.Code)
+		}
+		if rec := deliver(h, "/hook", completedConsumer, http.Header{"X-Signature": {"sha256=" + sig}}); rec.Code != http.StatusOK {
+			t.Errorf("expected 200 with valid signature, got %d", rec.Code)
+		}
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	h := webhook.NewHandler(webhook.WithoutVerification())
+	events, cancel := h.Subscribe(7)
+	other, cancelOther := h.Subscribe(8)
+	defer cancelOther()
+
+	deliver(h, "/hook", completedConsumer, nil)
+	select {
+	case e := <-events:
+		if e.Type != webhook.EventOrderCompleted {
+			t.Errorf("unexpected event %q", e.Type)
+		}
+	default:
+		t.Fatal("expected an event")
+	}
+	select {
+	case e := <-other:
+		t.Errorf("unexpected event for other consumer: %+v", e)
+	default:
+	}
+
+	cancel()
+	deliver(h, "/hook", `{"id": 7, "order": {"status": 3}}`, nil)
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event after cancel: %+v", e)
+	default:
+	}
+}
+
+var _ trenergy.OrderNotifier = (*webhook.Handler)(nil)
+
+func TestNewHandlerRequiresVerifier(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NewHandler to panic without a verifier")
+		}
+	}()
+	webhook.NewHandler()
+}
+
+func TestHandlerRedeliveryDuringDispatch(t *testing.T) {
+	h := webhook.NewHandler(webhook.WithoutVerification())
+	started, release := make(chan struct{}), make(chan struct{})
+	calls := 0
+	h.OnAny(func(context.Context, *webhook.Event) error {
+		calls++
+		if calls == 1 {
+			close(started)
+			<-release
+			return errors.New("database down")
+		}
+		return nil
+	})
+
+	first := make(chan int)
+	go func() { first <- deliver(h, "/hook", completedConsumer, nil).Code }()
+	<-started
+	if rec := deliver(h, "/hook", completedConsumer, nil); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 while the first delivery is processed, got %d", rec.Code)
+	}
+	close(release)
+	if code := <-first; code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", code)
+	}
+
+	if rec := deliver(h, "/hook", completedConsumer, nil); rec.Code != http.StatusOK || calls != 2 {
+		t.Errorf("expected the event to be processed again, got %d after %d calls", rec.Code, calls)
+	}
+}
+
+func TestHandlerPanicAllowsRedelivery(t *testing.T) {
+	h := webhook.NewHandler(webhook.WithoutVerification())
+	calls := 0
+	h.OnAny(func(context.Context, *webhook.Event) error {
+		calls++
+		if calls == 1 {
+			panic("handler bug")
+		}
+		return nil
+	})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the handler panic to propagate")
+			}
+		}()
+		deliver(h, "/hook", completedConsumer, nil)
+	}()
+	if rec := deliver(h, "/hook", completedConsumer, nil); rec.Code != http.StatusOK || calls != 2 {
+		t.Errorf("expected the redelivery to be processed, got %d after %d calls", rec.Code, calls)
+	}
+}
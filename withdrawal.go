@@ -7,12 +7,12 @@ import (
 
 // Withdrawal represents a withdrawal record.
 type Withdrawal struct {
-	ID        int     `json:"id"`
-	TrxAmount float64 `json:"trx_amount"`
-	Status    string  `json:"status"`
-	Address   string  `json:"address"`
-	CreatedAt string  `json:"created_at"`
-	UpdatedAt string  `json:"updated_at"`
+	ID        int       `json:"id"`
+	TrxAmount TRX       `json:"trx_amount"`
+	Status    string    `json:"status"`
+	Address   string    `json:"address"`
+	CreatedAt Timestamp `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
 }
 
 // ListWithdrawals retrieves a list of withdrawals.
@@ -27,20 +27,28 @@ func (c *Client) ListWithdrawals(ctx context.Context, page int) (*APIResponse[[]
 }
 
 // CreateWithdrawal creates a new withdrawal request.
-func (c *Client) CreateWithdrawal(ctx context.Context, amount float64, address string, otp string) (*APIResponse[struct{}], error) {
-	data := make(map[string]string)
-	data["trx_amount"] = fmt.Sprintf("%f", amount)
+func (c *Client) CreateWithdrawal(ctx context.Context, amount TRX, address string, otp string) (*APIResponse[struct{}], error) {
 	if address != "" {
-		data["address"] = address
-	}
-	if otp != "" {
-		data["one_time_password"] = otp
+		if err := ValidateAddress(address); err != nil {
+			return nil, err
+		}
 	}
+	form := struct {
+		TrxAmount TRX    `form:"trx_amount"`
+		Address   string `form:"address,omitempty"`
+		OTP       string `form:"one_time_password,omitempty"`
+	}{TrxAmount: amount, Address: address, OTP: otp}
 
+	spend, err := c.reserve(ctx, "withdrawal", amount, 0)
+	if err != nil {
+		return nil, err
+	}
 	var resp APIResponse[struct{}]
-	err := c.postMultipart(ctx, "/api/withdrawals", data, &resp)
+	err = c.sendForm(ctx, "POST", "/api/withdrawals", bodyMultipart, form, &resp)
 	if err != nil {
+		spend.fail(ctx, err)
 		return nil, err
 	}
+	spend.commit(ctx, amount)
 	return &resp, nil
 }